@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/analytics"
+	"github.com/bevane/safina-society-search/internal/views"
+)
+
+const defaultReportDays = 30
+
+// basicAuth protects the admin pages with the password set in
+// ADMIN_PASSWORD, the username is always "admin"
+func basicAuth(password string, next http.Handler) http.Handler {
+	// compare hashes so the comparison takes the same time regardless of
+	// the length of the submitted password
+	expected := sha256.Sum256([]byte(password))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, submitted, ok := r.BasicAuth()
+		hash := sha256.Sum256([]byte(submitted))
+		if !ok || username != "admin" || subtle.ConstantTimeCompare(hash[:], expected[:]) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (cfg *Config) handlerAdmin(w http.ResponseWriter, r *http.Request) {
+	report, days, err := cfg.analyticsReport(r)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to read analytics events", slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	err = views.AdminDashboard(cfg.collections[0], report, days, cfg.pageMeta(cfg.collections[0], "/admin")).Render(r.Context(), w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to render admin dashboard", slog.Any("error", err))
+	}
+}
+
+func (cfg *Config) handlerAdminExport(w http.ResponseWriter, r *http.Request) {
+	report, days, err := cfg.analyticsReport(r)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to read analytics events", slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="search-analytics-%dd.csv"`, days))
+	csvWriter := csv.NewWriter(w)
+	rows := [][]string{{"query", "searches", "zero_results", "clicks", "click_through_rate", "avg_latency_ms", "max_latency_ms"}}
+	for _, q := range report.Queries {
+		rows = append(rows, []string{
+			csvCell(q.Query),
+			strconv.Itoa(q.Searches),
+			strconv.Itoa(q.ZeroResults),
+			strconv.Itoa(q.Clicks),
+			strconv.FormatFloat(q.ClickThroughRate(), 'f', 4, 64),
+			strconv.FormatInt(q.AvgLatencyMs(), 10),
+			strconv.FormatInt(q.MaxLatencyMs, 10),
+		})
+	}
+	err = csvWriter.WriteAll(rows)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write analytics csv", slog.Any("error", err))
+	}
+}
+
+// csvCell keeps text typed by users from being read as a formula when the
+// export is opened in a spreadsheet, by prefixing cells that start with a
+// formula character with a quote
+func csvCell(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// analyticsReport summarizes the events of the period given by the days
+// query parameter
+func (cfg *Config) analyticsReport(r *http.Request) (analytics.Report, int, error) {
+	days, err := strconv.Atoi(r.URL.Query().Get("days"))
+	if err != nil || days < 1 {
+		days = defaultReportDays
+	}
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -days+1)
+	events, err := cfg.analytics.Events(since)
+	if err != nil {
+		return analytics.Report{}, days, err
+	}
+	return analytics.Summarize(events, since), days, nil
+}
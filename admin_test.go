@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestCsvCell(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"patience", "patience"},
+		{"", ""},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1", "'+1"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := csvCell(tt.text); got != tt.want {
+			t.Errorf("csvCell(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
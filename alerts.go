@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/alerts"
+	"github.com/bevane/safina-society-search/internal/ingest"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/views"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// number of alerts a client can subscribe to in an hour
+const subscribesPerHour = 10
+
+// handlerSubscribe saves the search of the form as an email alert. The
+// alert is only delivered once the link emailed to the address is opened,
+// so that nobody can subscribe someone else
+func (cfg *Config) handlerSubscribe(w http.ResponseWriter, r *http.Request) {
+	collection, ok := cfg.collectionFromRequest(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if !cfg.subscribeLimiter.allow(cfg.clientIP(r), time.Now()) {
+		w.Header().Set("Retry-After", "3600")
+		cfg.renderAlertStatus(w, r, collection, http.StatusTooManyRequests, "Too many alerts", "Too many alerts were requested from your network, please try again later.")
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		cfg.renderAlertStatus(w, r, collection, http.StatusBadRequest, "Invalid request", "The form could not be read.")
+		return
+	}
+	subscription, ok := alertSubscription(collection, r.PostForm)
+	if !ok {
+		cfg.renderAlertStatus(w, r, collection, http.StatusBadRequest, "Invalid search", "Alerts need a search of more than 2 characters or a topic.")
+		return
+	}
+	address, err := mail.ParseAddress(r.PostFormValue("email"))
+	if err != nil {
+		cfg.renderAlertStatus(w, r, collection, http.StatusBadRequest, "Invalid email address", "Please enter a valid email address.")
+		return
+	}
+	subscription.Email = address.Address
+
+	subscription, err = cfg.alerts.Add(subscription)
+	if errors.Is(err, alerts.ErrQueryTooLong) {
+		cfg.renderAlertStatus(w, r, collection, http.StatusBadRequest, "Search too long", fmt.Sprintf("Alerts need a search of at most %d characters.", alerts.MaxQueryLength))
+		return
+	}
+	if errors.Is(err, alerts.ErrTooMany) {
+		cfg.renderAlertStatus(w, r, collection, http.StatusBadRequest, "Too many alerts", fmt.Sprintf("An email address can have at most %d alerts.", alerts.MaxPerEmail))
+		return
+	}
+	if errors.Is(err, alerts.ErrTooManyPending) {
+		cfg.renderAlertStatus(w, r, collection, http.StatusBadRequest, "Alerts waiting for confirmation", fmt.Sprintf("Open the links already sent to %s to confirm its alerts, unconfirmed alerts expire after %d hours.", subscription.Email, int(alerts.ConfirmWithin.Hours())))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to save alert", slog.Any("error", err))
+		cfg.renderAlertStatus(w, r, collection, http.StatusInternalServerError, "Internal Server Error", "The alert could not be saved, please try again later.")
+		return
+	}
+	confirmUrl := cfg.siteUrl + "/alerts/confirm?" + url.Values{"id": {subscription.Id}}.Encode()
+	err = cfg.mailer.Send(r.Context(), alerts.Message{
+		To:      subscription.Email,
+		Subject: "Confirm your alert for " + alerts.Describe(subscription),
+		Body: fmt.Sprintf("Open this link to get an email when new videos on %s match your search %s:\n\n%s\n\nIf you did not ask for this alert, ignore this email and none will be sent.\n",
+			collection.ChannelName, alerts.Describe(subscription), confirmUrl),
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to send alert confirmation", slog.Any("error", err))
+		err = cfg.alerts.Remove(subscription.Id)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "unable to remove unconfirmed alert", slog.Any("error", err))
+		}
+		cfg.renderAlertStatus(w, r, collection, http.StatusInternalServerError, "Internal Server Error", "The confirmation email could not be sent, please try again later.")
+		return
+	}
+	cfg.renderAlertStatus(w, r, collection, http.StatusOK, "Check your inbox", fmt.Sprintf("Open the link we sent to %s to start getting alerts for %s.", subscription.Email, alerts.Describe(subscription)))
+}
+
+// handlerConfirmAlert confirms the alert of the link of the confirmation
+// email
+func (cfg *Config) handlerConfirmAlert(w http.ResponseWriter, r *http.Request) {
+	subscription, err := cfg.alerts.Confirm(r.URL.Query().Get("id"))
+	if errors.Is(err, alerts.ErrNotFound) {
+		cfg.renderAlertStatus(w, r, cfg.collections[0], http.StatusNotFound, "Alert not found", fmt.Sprintf("This alert does not exist, was unsubscribed or was not confirmed within %d hours.", int(alerts.ConfirmWithin.Hours())))
+		return
+	}
+	if errors.Is(err, alerts.ErrTooMany) {
+		cfg.renderAlertStatus(w, r, cfg.collections[0], http.StatusBadRequest, "Too many alerts", fmt.Sprintf("An email address can have at most %d alerts.", alerts.MaxPerEmail))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to confirm alert", slog.Any("error", err))
+		cfg.renderAlertStatus(w, r, cfg.collections[0], http.StatusInternalServerError, "Internal Server Error", "The alert could not be confirmed, please try again later.")
+		return
+	}
+	collection, ok := cfg.collectionById(subscription.Collection)
+	if !ok {
+		collection = cfg.collections[0]
+	}
+	cfg.renderAlertStatus(w, r, collection, http.StatusOK, "Alert confirmed", fmt.Sprintf("You will get an email when new videos match your search %s.", alerts.Describe(subscription)))
+}
+
+// handlerConfirmUnsubscribe asks to confirm unsubscribing from the alert
+// of the unsubscribe link of a digest. Opening the link does not remove the
+// alert so that mail scanners following links do not unsubscribe anyone
+func (cfg *Config) handlerConfirmUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	subscription, err := cfg.alerts.Get(id)
+	if errors.Is(err, alerts.ErrNotFound) {
+		cfg.renderAlertStatus(w, r, cfg.collections[0], http.StatusNotFound, "Alert not found", "This alert does not exist or was already unsubscribed.")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to get alert", slog.Any("error", err))
+		cfg.renderAlertStatus(w, r, cfg.collections[0], http.StatusInternalServerError, "Internal Server Error", "The alert could not be found, please try again later.")
+		return
+	}
+	collection, ok := cfg.collectionById(subscription.Collection)
+	if !ok {
+		collection = cfg.collections[0]
+	}
+	meta := cfg.pageMeta(collection, collection.HomePath())
+	meta.Title = "Unsubscribe - " + meta.Title
+	unsubscribePath := "/alerts/unsubscribe?" + url.Values{"id": {id}}.Encode()
+	err = views.ConfirmUnsubscribe(collection, unsubscribePath, alerts.Describe(subscription), meta).Render(r.Context(), w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to render unsubscribe confirmation", slog.Any("error", err))
+	}
+}
+
+// handlerUnsubscribe removes the alert of the unsubscribe link of a digest
+// once it is confirmed. Mail clients unsubscribe with a POST to the
+// List-Unsubscribe url
+func (cfg *Config) handlerUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	err := cfg.alerts.Remove(r.URL.Query().Get("id"))
+	if err != nil && !errors.Is(err, alerts.ErrNotFound) {
+		slog.ErrorContext(r.Context(), "unable to remove alert", slog.Any("error", err))
+		cfg.renderAlertStatus(w, r, cfg.collections[0], http.StatusInternalServerError, "Internal Server Error", "The alert could not be removed, please try again later.")
+		return
+	}
+	// unsubscribing twice is not an error
+	cfg.renderAlertStatus(w, r, cfg.collections[0], http.StatusOK, "Unsubscribed", "You will not get any more emails for this alert.")
+}
+
+func (cfg *Config) renderAlertStatus(w http.ResponseWriter, r *http.Request, collection model.Collection, status int, title string, message string) {
+	meta := cfg.pageMeta(collection, collection.HomePath())
+	meta.Title = title + " - " + meta.Title
+	w.WriteHeader(status)
+	err := views.AlertStatus(collection, title, message, meta).Render(r.Context(), w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to render alert status", slog.Any("error", err))
+	}
+}
+
+// alertSubscription returns the subscription to the search of the params,
+// false if it is not a valid search. Searches within a video are not
+// valid as a video is never new
+func alertSubscription(collection model.Collection, params url.Values) (alerts.Subscription, bool) {
+	state := model.SearchStateFromValues(params)
+	// the query is written in emails, so it is kept on a single line
+	query := strings.Join(strings.Fields(state.Query), " ")
+	topic := searchTopic(state.Topic)
+	if (query == "" && topic == "") || (query != "" && len(query) <= 2) {
+		return alerts.Subscription{}, false
+	}
+	if _, ok := parseVideoQuery(query); ok {
+		return alerts.Subscription{}, false
+	}
+	return alerts.Subscription{
+		Collection: collection.Id,
+		Query:      query,
+		Lang:       searchLanguage(state.Lang),
+		Topic:      topic,
+	}, true
+}
+
+// sendAlerts delivers a digest of the newly ingested videos of the index to
+// every confirmed subscription they match, and returns the number of
+// digests delivered. A failed delivery does not stop the others
+func (cfg *Config) sendAlerts(ctx context.Context, indexName string, videoIds []string) (int, error) {
+	subscriptions, err := cfg.alerts.List()
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	var errs []error
+	for _, subscription := range subscriptions {
+		if !subscription.Confirmed {
+			continue
+		}
+		collection, ok := cfg.collectionById(subscription.Collection)
+		if !ok {
+			continue
+		}
+		for _, searched := range cfg.searchedCollections(collection) {
+			if searched.IndexName != indexName {
+				continue
+			}
+			digest, err := cfg.alertDigest(ctx, collection, searched, subscription, videoIds)
+			if err == nil && len(digest.Videos) > 0 {
+				err = cfg.notifiers.Notify(ctx, digest)
+				if err == nil {
+					sent++
+				}
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "unable to send alert", slog.String("subscription", subscription.Id), slog.Any("error", err))
+				errs = append(errs, err)
+			}
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// alertDigest returns the digest of the videos that match the subscription
+// among those with the given ids in the index of the searched collection
+func (cfg *Config) alertDigest(ctx context.Context, collection model.Collection, searched model.Collection, subscription alerts.Subscription, videoIds []string) (alerts.Digest, error) {
+	state := model.SearchState{Query: subscription.Query, Lang: subscription.Lang, Topic: subscription.Topic, Page: 1}
+	digest := alerts.Digest{
+		Subscription:   subscription,
+		SearchUrl:      cfg.siteUrl + collection.SearchUrl(state),
+		UnsubscribeUrl: cfg.siteUrl + "/alerts/unsubscribe?" + url.Values{"id": {subscription.Id}}.Encode(),
+	}
+	transcriptField := model.TranscriptField(state.Lang)
+	// a search returns at most MaxTotalHits hits, so a sync that ingested
+	// more videos is searched in batches
+	for batch := range slices.Chunk(videoIds, ingest.MaxTotalHits) {
+		filter := idFilter(batch)
+		if filter == "" {
+			continue
+		}
+		searchRequest := meilisearch.SearchRequest{
+			AttributesToSearchOn: []string{"title", transcriptField},
+			AttributesToRetrieve: []string{"id", "title", transcriptField},
+			ShowMatchesPosition:  true,
+			Limit:                int64(len(batch)),
+		}
+		filterSearch(&searchRequest, state, filter)
+		response, err := searchIndex(ctx, searched.IndexName, state.Query, &searchRequest, cfg.searchClient)
+		if err != nil {
+			return digest, err
+		}
+		for _, hit := range response.Hits {
+			moment, snippet, _ := bestMoment(ctx, hit, state.Lang)
+			digest.Videos = append(digest.Videos, alerts.Video{
+				Id:        hit.Id,
+				Title:     hit.Title,
+				Url:       moment.Watch(),
+				Timestamp: snippet.Timestamp,
+				Snippet:   snippet.Text.String(),
+			})
+		}
+	}
+	return digest, nil
+}
+
+// runAlerts adds, lists and removes alerts. Webhook alerts can only be added
+// here as the server would otherwise post to any url entered in a form
+func (cfg *Config) runAlerts(args []string) error {
+	if cfg.alerts == nil {
+		return errors.New("ALERTS_FILE is not set")
+	}
+	if len(args) == 0 {
+		return errors.New("missing subcommand, available subcommands: add, list, remove")
+	}
+	switch args[0] {
+	case "add":
+		return cfg.runAlertsAdd(args[1:])
+	case "list":
+		return cfg.runAlertsList()
+	case "remove":
+		return cfg.runAlertsRemove(args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand %q, available subcommands: add, list, remove", args[0])
+	}
+}
+
+func (cfg *Config) runAlertsAdd(args []string) error {
+	flags := flag.NewFlagSet("alerts add", flag.ContinueOnError)
+	collectionId := flags.String("collection", cfg.collections[0].Id, "id of the collection to search")
+	query := flags.String("q", "", "search query")
+	lang := flags.String("lang", "", "language code of the transcripts to search")
+	topic := flags.String("topic", "", "topic the videos are about")
+	email := flags.String("email", "", "email address to deliver digests to, confirmed without a confirmation email")
+	webhook := flags.String("webhook", "", "url to post digests to as JSON")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	collection, ok := cfg.collectionById(*collectionId)
+	if !ok {
+		return fmt.Errorf("unknown collection %q", *collectionId)
+	}
+	subscription, ok := alertSubscription(collection, url.Values{
+		model.ParamQuery: {*query},
+		model.ParamLang:  {*lang},
+		model.ParamTopic: {*topic},
+	})
+	if !ok {
+		return errors.New("-q must be longer than 2 characters or -topic must be set")
+	}
+	switch {
+	case (*email == "") == (*webhook == ""):
+		return errors.New("exactly one of -email and -webhook is required")
+	case *email != "":
+		address, err := mail.ParseAddress(*email)
+		if err != nil {
+			return err
+		}
+		subscription.Email = address.Address
+	default:
+		u, err := url.Parse(*webhook)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid webhook url %q", *webhook)
+		}
+		subscription.WebhookUrl = *webhook
+	}
+	subscription.Confirmed = true
+	subscription, err = cfg.alerts.Add(subscription)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("added alert %s for %s\n", subscription.Id, alerts.Describe(subscription))
+	return nil
+}
+
+func (cfg *Config) runAlertsList() error {
+	subscriptions, err := cfg.alerts.List()
+	if err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		delivery := subscription.Email
+		if subscription.WebhookUrl != "" {
+			delivery = subscription.WebhookUrl
+		}
+		status := "confirmed"
+		if !subscription.Confirmed {
+			status = "unconfirmed"
+		}
+		fmt.Printf("%s %s %s %s to %s\n", subscription.Id, subscription.Collection, status, alerts.Describe(subscription), delivery)
+	}
+	return nil
+}
+
+func (cfg *Config) runAlertsRemove(args []string) error {
+	flags := flag.NewFlagSet("alerts remove", flag.ContinueOnError)
+	id := flags.String("id", "", "id of the alert to remove")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = cfg.alerts.Remove(*id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("removed alert %s\n", *id)
+	return nil
+}
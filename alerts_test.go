@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/alerts"
+	"github.com/bevane/safina-society-search/internal/ingest"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// quoted video ids in a filter
+var idPattern = regexp.MustCompile(`"[A-Za-z0-9_-]{11}"`)
+
+// recordingMailer keeps the messages it is asked to send
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []alerts.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, message alerts.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+func newAlertsConfig(t *testing.T) (*Config, *recordingMailer) {
+	t.Helper()
+	store, err := alerts.NewStore(filepath.Join(t.TempDir(), "alerts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer := &recordingMailer{}
+	cfg := &Config{
+		collections:      []model.Collection{{Id: "safina", IndexName: "videos", ChannelName: "Safina Society"}},
+		siteUrl:          "https://example.com",
+		alerts:           store,
+		mailer:           mailer,
+		subscribeLimiter: newRateLimiter(subscribesPerHour, time.Hour),
+	}
+	return cfg, mailer
+}
+
+func subscribe(cfg *Config, remoteAddr string, query string, email string) int {
+	form := url.Values{"q": {query}, "email": {email}}
+	r := httptest.NewRequest(http.MethodPost, "/alerts", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	cfg.handlerSubscribe(w, r)
+	return w.Code
+}
+
+func TestHandlerSubscribe(t *testing.T) {
+	cfg, mailer := newAlertsConfig(t)
+	if code := subscribe(cfg, "192.0.2.1:1234", "patience", "reader@example.com"); code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+	if len(mailer.messages) != 1 || !strings.Contains(mailer.messages[0].Body, "https://example.com/alerts/confirm?id=") {
+		t.Errorf("messages = %+v, want a confirmation email", mailer.messages)
+	}
+	if code := subscribe(cfg, "192.0.2.1:1234", strings.Repeat("patience ", 30), "reader@example.com"); code != http.StatusBadRequest {
+		t.Errorf("status %d for a long query, want 400", code)
+	}
+	// the pending confirmations of an address are capped
+	for range alerts.MaxPendingPerEmail - 1 {
+		subscribe(cfg, "192.0.2.1:1234", "gratitude", "reader@example.com")
+	}
+	if code := subscribe(cfg, "192.0.2.1:1234", "gratitude", "reader@example.com"); code != http.StatusBadRequest {
+		t.Errorf("status %d over the pending cap, want 400", code)
+	}
+	if len(mailer.messages) != alerts.MaxPendingPerEmail {
+		t.Errorf("sent %d confirmations, want %d", len(mailer.messages), alerts.MaxPendingPerEmail)
+	}
+}
+
+func TestHandlerSubscribeRateLimit(t *testing.T) {
+	cfg, mailer := newAlertsConfig(t)
+	for i := range subscribesPerHour {
+		email := strings.Repeat("a", i+1) + "@example.com"
+		if code := subscribe(cfg, "192.0.2.1:1234", "patience", email); code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i+1, code)
+		}
+	}
+	if code := subscribe(cfg, "192.0.2.1:5678", "patience", "other@example.com"); code != http.StatusTooManyRequests {
+		t.Errorf("status %d over the limit, want 429", code)
+	}
+	if code := subscribe(cfg, "198.51.100.1:1234", "patience", "other@example.com"); code != http.StatusOK {
+		t.Errorf("status %d for another client, want 200", code)
+	}
+	if len(mailer.messages) != subscribesPerHour+1 {
+		t.Errorf("sent %d confirmations, want %d", len(mailer.messages), subscribesPerHour+1)
+	}
+}
+
+func TestHandlerSubscribeBehindProxy(t *testing.T) {
+	cfg, _ := newAlertsConfig(t)
+	cfg.trustedProxies, _ = parseTrustedProxies("127.0.0.1")
+	subscribeVia := func(client string, i int) int {
+		form := url.Values{"q": {fmt.Sprintf("patience %d", i)}, "email": {fmt.Sprintf("reader%d@example.com", i)}}
+		r := httptest.NewRequest(http.MethodPost, "/alerts", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("X-Forwarded-For", client)
+		r.RemoteAddr = "127.0.0.1:1234"
+		w := httptest.NewRecorder()
+		cfg.handlerSubscribe(w, r)
+		return w.Code
+	}
+	for i := range subscribesPerHour {
+		subscribeVia("192.0.2.1", i)
+	}
+	if code := subscribeVia("192.0.2.1", subscribesPerHour); code != http.StatusTooManyRequests {
+		t.Errorf("status %d over the limit, want 429", code)
+	}
+	// another visitor behind the same proxy is not limited
+	if code := subscribeVia("192.0.2.2", subscribesPerHour+1); code != http.StatusOK {
+		t.Errorf("status %d for another client behind the proxy, want 200", code)
+	}
+}
+
+func TestHandlerUnsubscribe(t *testing.T) {
+	cfg, _ := newAlertsConfig(t)
+	subscription, err := cfg.alerts.Add(alerts.Subscription{Collection: "safina", Query: "patience", Email: "reader@example.com", Confirmed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsubscribeUrl := "/alerts/unsubscribe?id=" + subscription.Id
+
+	// opening the link, as a mail scanner would, only asks to confirm
+	w := httptest.NewRecorder()
+	cfg.handlerConfirmUnsubscribe(w, httptest.NewRequest(http.MethodGet, unsubscribeUrl, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<form method="post" action="`+unsubscribeUrl+`"`) {
+		t.Errorf("status %d, want a form posting to %s:\n%s", w.Code, unsubscribeUrl, w.Body.String())
+	}
+	if _, err := cfg.alerts.Get(subscription.Id); err != nil {
+		t.Fatalf("opening the link removed the alert: %v", err)
+	}
+
+	// one click unsubscribe of mail clients and the form post
+	r := httptest.NewRequest(http.MethodPost, unsubscribeUrl, strings.NewReader("List-Unsubscribe=One-Click"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	cfg.handlerUnsubscribe(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("status %d, want 200", w.Code)
+	}
+	if _, err := cfg.alerts.Get(subscription.Id); !errors.Is(err, alerts.ErrNotFound) {
+		t.Errorf("alert was not removed: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	cfg.handlerConfirmUnsubscribe(w, httptest.NewRequest(http.MethodGet, unsubscribeUrl, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status %d for a removed alert, want 404", w.Code)
+	}
+}
+
+func TestAlertDigestBatches(t *testing.T) {
+	cfg, _ := newAlertsConfig(t)
+	var limits []int64
+	cfg.searchClient = fakeMeilisearch(t, func(w http.ResponseWriter, r *http.Request) {
+		var body meilisearch.SearchRequest
+		decodeBody(t, r, &body)
+		limits = append(limits, body.Limit)
+		// every video of the filter matches
+		filter, _ := body.Filter.(string)
+		ids := idPattern.FindAllString(filter, -1)
+		if int64(len(ids)) > body.Limit {
+			t.Errorf("the filter has %d ids and the limit is %d", len(ids), body.Limit)
+		}
+		var hits []map[string]any
+		for _, id := range ids {
+			hits = append(hits, map[string]any{"id": strings.Trim(id, `"`), "title": "Patience"})
+		}
+		writeJSON(w, map[string]any{"hits": hits})
+	})
+	var videoIds []string
+	for i := range 120 {
+		videoIds = append(videoIds, fmt.Sprintf("video%06d", i))
+	}
+	subscription := alerts.Subscription{Id: "abc", Collection: "safina", Query: "patience", Email: "reader@example.com", Confirmed: true}
+	digest, err := cfg.alertDigest(context.Background(), cfg.collections[0], cfg.collections[0], subscription, videoIds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(digest.Videos) != len(videoIds) {
+		t.Errorf("digest has %d videos, want %d", len(digest.Videos), len(videoIds))
+	}
+	if want := []int64{ingest.MaxTotalHits, ingest.MaxTotalHits, 20}; !slices.Equal(limits, want) {
+		t.Errorf("searched with limits %v, want %v", limits, want)
+	}
+}
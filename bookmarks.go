@@ -0,0 +1,260 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/bookmarks"
+	"github.com/bevane/safina-society-search/internal/link"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/views"
+)
+
+// holds the anonymous token that owns the bookmark collections made in the
+// browser
+const bookmarksCookie = "bookmarks"
+
+// bookmarkOwner returns the anonymous token of the browser, "" if it has
+// not saved a moment yet
+func bookmarkOwner(r *http.Request) string {
+	cookie, err := r.Cookie(bookmarksCookie)
+	if err != nil || len(cookie.Value) != 32 {
+		return ""
+	}
+	return cookie.Value
+}
+
+// ensureBookmarkOwner returns the anonymous token of the browser and gives
+// it one if it has none
+func ensureBookmarkOwner(w http.ResponseWriter, r *http.Request) (string, error) {
+	if owner := bookmarkOwner(r); owner != "" {
+		return owner, nil
+	}
+	owner, err := bookmarks.NewToken()
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     bookmarksCookie,
+		Value:    owner,
+		Path:     "/",
+		MaxAge:   int((5 * 365 * 24 * time.Hour).Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return owner, nil
+}
+
+// saveMomentPath loads the form saving the moment of a video
+func saveMomentPath(videoId string, timestampSeconds string, lang string) string {
+	params := url.Values{"v": {videoId}, "t": {timestampSeconds}}
+	if lang != "" {
+		params.Set(model.ParamLang, lang)
+	}
+	return "/collections/save?" + params.Encode()
+}
+
+// handlerSaveMomentForm renders the form saving a moment to one of the
+// collections of the browser or to a new one
+func (cfg *Config) handlerSaveMomentForm(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if !link.ValidVideoId(params.Get("v")) {
+		http.Error(w, "invalid video id", http.StatusBadRequest)
+		return
+	}
+	lists, err := cfg.bookmarks.Lists(bookmarkOwner(r))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to list bookmark collections", slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	err = views.SaveMoment(lists, params.Get("v"), params.Get("t"), searchLanguage(params.Get(model.ParamLang))).Render(r.Context(), w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to render save moment form", slog.Any("error", err))
+	}
+}
+
+// handlerSaveMoment saves a moment to a collection. The title and
+// transcript of the moment are read from the index rather than the form
+func (cfg *Config) handlerSaveMoment(w http.ResponseWriter, r *http.Request) {
+	videoId := r.PostFormValue("v")
+	if !link.ValidVideoId(videoId) {
+		http.Error(w, "invalid video id", http.StatusBadRequest)
+		return
+	}
+	start, err := strconv.Atoi(r.PostFormValue("t"))
+	if err != nil || start < 0 {
+		http.Error(w, "invalid timestamp", http.StatusBadRequest)
+		return
+	}
+	lang := searchLanguage(r.PostFormValue(model.ParamLang))
+	document, _, err := cfg.findVideo(r.Context(), videoId, []string{"id", "title", "transcript", "transcripts"})
+	if errors.Is(err, errVideoNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to get video", slog.String("videoId", videoId), slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	hit := model.VideoHit{Transcript: document.Transcript, Transcripts: document.Transcripts}
+	moment := bookmarks.Moment{
+		VideoId: videoId,
+		Title:   document.Title,
+		Start:   start,
+		Lang:    lang,
+		Snippet: passageSnippet(hit.TranscriptIn(lang), start, snippetWords),
+		Note:    r.PostFormValue("note"),
+	}
+
+	owner, err := ensureBookmarkOwner(w, r)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to create bookmark owner", slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	list, err := cfg.bookmarks.Save(owner, r.PostFormValue("collection"), r.PostFormValue("name"), moment)
+	switch {
+	case errors.Is(err, bookmarks.ErrNotFound):
+		http.NotFound(w, r)
+		return
+	case errors.Is(err, bookmarks.ErrInvalidName):
+		http.Error(w, fmt.Sprintf("the collection needs a name of at most %d characters", bookmarks.MaxNameLength), http.StatusBadRequest)
+		return
+	case errors.Is(err, bookmarks.ErrNoteTooLong):
+		http.Error(w, fmt.Sprintf("the note can be at most %d characters", bookmarks.MaxNoteLength), http.StatusBadRequest)
+		return
+	case errors.Is(err, bookmarks.ErrTooMany):
+		http.Error(w, fmt.Sprintf("at most %d collections of %d moments can be saved", bookmarks.MaxLists, bookmarks.MaxMoments), http.StatusBadRequest)
+		return
+	case errors.Is(err, bookmarks.ErrFull):
+		slog.ErrorContext(r.Context(), "unable to save moment", slog.Any("error", err))
+		http.Error(w, "no more moments can be saved at the moment", http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "unable to save moment", slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if r.Header.Get("Hx-Request") == "" {
+		http.Redirect(w, r, views.BookmarkListPath(list.Id), http.StatusSeeOther)
+		return
+	}
+	err = views.MomentSaved(list).Render(r.Context(), w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to render saved moment", slog.Any("error", err))
+	}
+}
+
+// handlerBookmarkLists lists the collections of the browser
+func (cfg *Config) handlerBookmarkLists(w http.ResponseWriter, r *http.Request) {
+	lists, err := cfg.bookmarks.Lists(bookmarkOwner(r))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to list bookmark collections", slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	collection := cfg.collections[0]
+	meta := cfg.pageMeta(collection, "/collections")
+	meta.Title = "Your collections - " + meta.Title
+	err = views.BookmarkLists(collection, lists, meta).Render(r.Context(), w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to render bookmark collections", slog.Any("error", err))
+	}
+}
+
+// handlerBookmarkList shows a collection, anyone with the link can see it
+// but only the browser that made it can change it
+func (cfg *Config) handlerBookmarkList(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.bookmarkList(w, r)
+	if !ok {
+		return
+	}
+	collection := cfg.collections[0]
+	meta := cfg.pageMeta(collection, views.BookmarkListPath(list.Id))
+	meta.Title = list.Name + " - " + meta.Title
+	meta.Description = fmt.Sprintf("%d moments saved from %s", len(list.Moments), collection.ChannelName)
+	if len(list.Moments) > 0 {
+		meta.ImageUrl = link.Thumbnail(list.Moments[0].VideoId)
+	}
+	err := views.BookmarkList(collection, list, list.OwnedBy(bookmarkOwner(r)), meta).Render(r.Context(), w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to render bookmark collection", slog.Any("error", err))
+	}
+}
+
+// handlerExportBookmarkList downloads a collection as Markdown or JSON
+func (cfg *Config) handlerExportBookmarkList(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, ok := cfg.bookmarkList(w, r)
+		if !ok {
+			return
+		}
+		shareUrl := cfg.siteUrl + views.BookmarkListPath(list.Id)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", list.Id+"."+format))
+		var err error
+		if format == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			err = bookmarks.WriteJSON(w, list, shareUrl)
+		} else {
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			err = bookmarks.WriteMarkdown(w, list, shareUrl)
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "unable to export bookmark collection", slog.Any("error", err))
+		}
+	}
+}
+
+// handlerRemoveMoment removes a moment from a collection of the browser
+func (cfg *Config) handlerRemoveMoment(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := cfg.bookmarks.RemoveMoment(bookmarkOwner(r), id, r.PostFormValue("moment"))
+	if errors.Is(err, bookmarks.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to remove moment", slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, views.BookmarkListPath(id), http.StatusSeeOther)
+}
+
+// handlerDeleteBookmarkList deletes a collection of the browser
+func (cfg *Config) handlerDeleteBookmarkList(w http.ResponseWriter, r *http.Request) {
+	err := cfg.bookmarks.Delete(bookmarkOwner(r), r.PathValue("id"))
+	if errors.Is(err, bookmarks.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to delete bookmark collection", slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/collections", http.StatusSeeOther)
+}
+
+// bookmarkList returns the collection of the id in the path, or responds
+// with an error and false
+func (cfg *Config) bookmarkList(w http.ResponseWriter, r *http.Request) (bookmarks.List, bool) {
+	list, err := cfg.bookmarks.Get(r.PathValue("id"))
+	if errors.Is(err, bookmarks.ErrNotFound) {
+		http.NotFound(w, r)
+		return bookmarks.List{}, false
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to get bookmark collection", slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return bookmarks.List{}, false
+	}
+	return list, true
+}
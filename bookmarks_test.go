@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bevane/safina-society-search/internal/bookmarks"
+	"github.com/bevane/safina-society-search/internal/jsonfile"
+	"github.com/bevane/safina-society-search/internal/model"
+)
+
+func TestHandlerSaveMomentErrors(t *testing.T) {
+	client := fakeMeilisearch(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"id": "AbCdEfGhIj0", "title": "Patience", "transcript": "00:00:01.000\npatience is a virtue"})
+	})
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	store, err := bookmarks.NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{
+		searchClient: client,
+		collections:  []model.Collection{{Id: "safina", IndexName: "videos", ChannelName: "Safina Society"}},
+		bookmarks:    store,
+	}
+	save := func(form url.Values) *httptest.ResponseRecorder {
+		form.Set("v", "AbCdEfGhIj0")
+		form.Set("t", "1")
+		r := httptest.NewRequest(http.MethodPost, "/collections/moments", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		cfg.handlerSaveMoment(w, r)
+		return w
+	}
+
+	w := save(url.Values{"name": {"Patience"}, "note": {strings.Repeat("a", bookmarks.MaxNoteLength+1)}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "note") {
+		t.Errorf("long note: %d %q, want 400 about the note", w.Code, w.Body.String())
+	}
+	w = save(url.Values{"name": {""}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("empty name: %d, want 400", w.Code)
+	}
+
+	var lists []bookmarks.List
+	for i := range bookmarks.MaxTotalLists {
+		lists = append(lists, bookmarks.List{Id: fmt.Sprint(i), Owner: fmt.Sprint("owner", i)})
+	}
+	err = jsonfile.Write(path, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the store reads the file once, so a new store reads the full file
+	cfg.bookmarks, err = bookmarks.NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = save(url.Values{"name": {"Patience"}})
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("full store: %d, want 503", w.Code)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/bevane/safina-society-search/internal/model"
+)
+
+var collectionIdRegex = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// defaultCollections is used when no collections file is configured
+var defaultCollections = []model.Collection{
+	{
+		Id:          "safina",
+		IndexName:   "videos",
+		ChannelName: "Safina Society",
+		LogoUrl:     "/public/logo.png",
+		Description: "Search through Safina Society's YouTube videos",
+		Semantic:    true,
+	},
+}
+
+// loadCollections reads the collection registry from a JSON array of
+// collections. The first collection is the default collection served from
+// the root. If more than one collection is registered, a collection that
+// searches all of them is added. Semantic search is disabled for every
+// collection if no embedder is configured
+func loadCollections(path string, semantic bool) ([]model.Collection, error) {
+	collections := slices.Clone(defaultCollections)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		collections = nil
+		err = json.Unmarshal(data, &collections)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse collections file: %w", err)
+		}
+	}
+	if len(collections) == 0 {
+		return nil, errors.New("no collections registered")
+	}
+
+	seen := map[string]bool{}
+	for i, collection := range collections {
+		if !collectionIdRegex.MatchString(collection.Id) || collection.Id == model.AllCollectionsId {
+			return nil, fmt.Errorf("invalid collection id %q", collection.Id)
+		}
+		if seen[collection.Id] {
+			return nil, fmt.Errorf("duplicate collection id %q", collection.Id)
+		}
+		if collection.IndexName == "" {
+			return nil, fmt.Errorf("collection %q has no index name", collection.Id)
+		}
+		seen[collection.Id] = true
+		collections[i].Semantic = collection.Semantic && semantic
+		if i > 0 {
+			collections[i].Path = "/c/" + collection.Id
+		}
+	}
+
+	if len(collections) > 1 {
+		// every index has to store vectors to search them all by meaning
+		allSemantic := true
+		for _, collection := range collections {
+			allSemantic = allSemantic && collection.Semantic
+		}
+		collections = append(collections, model.Collection{
+			Id:          model.AllCollectionsId,
+			ChannelName: "All Channels",
+			LogoUrl:     collections[0].LogoUrl,
+			Description: "Search through the videos of every channel",
+			Path:        "/c/" + model.AllCollectionsId,
+			Semantic:    allSemantic,
+		})
+	}
+	return collections, nil
+}
+
+// collectionFromRequest returns the collection named in the url path or
+// the default collection for routes outside of /c/
+func (cfg *Config) collectionFromRequest(r *http.Request) (model.Collection, bool) {
+	id := r.PathValue("collection")
+	if id == "" {
+		return cfg.collections[0], true
+	}
+	return cfg.collectionById(id)
+}
+
+func (cfg *Config) collectionById(id string) (model.Collection, bool) {
+	for _, collection := range cfg.collections {
+		if collection.Id == id {
+			return collection, true
+		}
+	}
+	return model.Collection{}, false
+}
+
+// searchedCollections returns the collections whose indexes are searched
+// when searching the given collection
+func (cfg *Config) searchedCollections(collection model.Collection) []model.Collection {
+	if !collection.IsAll() {
+		return []model.Collection{collection}
+	}
+	return cfg.indexedCollections()
+}
+
+// indexedCollections returns the collections that have their own index
+func (cfg *Config) indexedCollections() []model.Collection {
+	var collections []model.Collection
+	for _, c := range cfg.collections {
+		if !c.IsAll() {
+			collections = append(collections, c)
+		}
+	}
+	return collections
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bevane/safina-society-search/internal/model"
+)
+
+// the search url of a state in a collection is served by the search of the
+// collection with the same state
+func TestSearchUrlRoundTrip(t *testing.T) {
+	cfg := &Config{collections: []model.Collection{
+		{Id: "safina", IndexName: "videos"},
+		{Id: "other", IndexName: "other", Path: "/c/other"},
+		{Id: model.AllCollectionsId, Path: "/c/" + model.AllCollectionsId},
+	}}
+	var collection model.Collection
+	var state model.SearchState
+	serveMux := http.NewServeMux()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var ok bool
+		collection, ok = cfg.collectionFromRequest(r)
+		if !ok {
+			http.NotFound(w, r)
+		}
+		state = model.SearchStateFromValues(r.URL.Query())
+	}
+	serveMux.HandleFunc("GET /search", handler)
+	serveMux.HandleFunc("GET /c/{collection}/search", handler)
+
+	states := []model.SearchState{
+		{Query: "patience", Page: 1},
+		{Query: "sabr & shukr = 100% #1 +", Lang: "ar", Topic: "patience", Page: 3},
+		{Query: "بسم الله", Lang: "ur", Mode: model.SearchModeHybrid, Page: 5},
+		{Query: "id:AbCdEfGhIj0 40m-1h5m patience", Mode: model.SearchModeHybrid, Page: 2},
+		{Query: "id:AbCdEfGhIj0 45:00-"},
+		{Topic: "prayer", Page: 1},
+	}
+	for _, c := range cfg.collections {
+		for _, want := range states {
+			searchUrl := c.SearchUrl(want)
+			w := httptest.NewRecorder()
+			serveMux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, searchUrl, nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("%s is not served: %d", searchUrl, w.Code)
+				continue
+			}
+			if collection.Id != c.Id || state != want {
+				t.Errorf("%s is the search %+v in %s, want %+v in %s", searchUrl, state, collection.Id, want, c.Id)
+			}
+			// the time range of a search within a video survives the url
+			wantQuery, wantOk := parseVideoQuery(want.Query)
+			gotQuery, gotOk := parseVideoQuery(state.Query)
+			if wantOk != gotOk || wantQuery.Start != gotQuery.Start || wantQuery.End != gotQuery.End {
+				t.Errorf("%s searches %+v, want %+v", searchUrl, gotQuery, wantQuery)
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/ingest"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/transcript"
+)
+
+func runCommand(cfg *Config, name string, args []string) error {
+	switch name {
+	case "sync":
+		return cfg.runSync(args)
+	case "settings":
+		return cfg.runSettings(args)
+	case "embed":
+		return cfg.runEmbed(args)
+	case "related":
+		return cfg.runRelated(args)
+	case "topics":
+		return cfg.runTopics(args)
+	case "alerts":
+		return cfg.runAlerts(args)
+	default:
+		return fmt.Errorf("unknown command %q, available commands: sync, settings, embed, related, topics, alerts", name)
+	}
+}
+
+// runSync ingests new and changed videos listed in a channel uploads feed
+// saved to disk
+func (cfg *Config) runSync(args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	feedPath := flags.String("feed", "", "path to the channel uploads list (YouTube Data API JSON or Atom feed)")
+	indexName := flags.String("index", "videos", "name of the meilisearch index to sync")
+	dryRun := flags.Bool("dry-run", false, "only report new and changed videos without ingesting them")
+	languages := flags.String("langs", model.DefaultLanguage, "comma separated codes of the languages to fetch transcripts in")
+	subsDir := flags.String("subs-dir", "", "directory of subtitles downloaded with yt-dlp (<id>.<lang>.vtt or .srt)")
+	whisperDir := flags.String("whisper-dir", "", "directory of transcripts generated with whisper (<id>.json or <id>.srt)")
+	timedText := flags.Bool("timedtext", false, "download captions from youtube's timedtext endpoint")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *feedPath == "" {
+		return errors.New("-feed is required")
+	}
+
+	f, err := os.Open(*feedPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	uploads, err := ingest.ParseUploads(f)
+	if err != nil {
+		return err
+	}
+
+	// providers are tried in order, local files before downloading
+	var providers transcript.Chain
+	if *subsDir != "" {
+		providers = append(providers, transcript.YtDlpProvider{Dir: *subsDir})
+	}
+	if *whisperDir != "" {
+		providers = append(providers, transcript.WhisperProvider{Dir: *whisperDir})
+	}
+	if *timedText {
+		providers = append(providers, transcript.TimedTextProvider{Client: &http.Client{Timeout: 30 * time.Second}})
+	}
+	options := ingest.SyncOptions{DryRun: *dryRun, Embedder: cfg.embedder}
+	for _, code := range strings.Split(*languages, ",") {
+		code = strings.TrimSpace(code)
+		if _, ok := model.LanguageByCode(code); !ok {
+			return fmt.Errorf("unsupported language %q", code)
+		}
+		options.Languages = append(options.Languages, code)
+	}
+	if len(providers) > 0 {
+		options.Transcripts = providers
+	}
+
+	index := ingest.NewMeiliIndex(cfg.searchClient, *indexName)
+	report, err := ingest.Sync(context.Background(), index, uploads, options)
+	if err != nil {
+		return err
+	}
+	printSyncReport(report, *dryRun)
+	// the new videos are matched against the saved searches once they are
+	// searchable
+	if *dryRun || len(report.New) == 0 || cfg.alerts == nil {
+		return nil
+	}
+	var videoIds []string
+	for _, upload := range report.New {
+		videoIds = append(videoIds, upload.Id)
+	}
+	sent, err := cfg.sendAlerts(context.Background(), *indexName, videoIds)
+	fmt.Printf("%d alerts sent\n", sent)
+	if err != nil {
+		return fmt.Errorf("unable to send alerts: %w", err)
+	}
+	return nil
+}
+
+func printSyncReport(report ingest.SyncReport, dryRun bool) {
+	action := "ingested"
+	if dryRun {
+		action = "to ingest"
+	}
+	fmt.Printf("%d new, %d changed, %d unchanged videos\n", len(report.New), len(report.Changed), report.Unchanged)
+	for _, upload := range report.New {
+		fmt.Printf("new (%s): %s %s\n", action, upload.Id, upload.Title)
+	}
+	for _, upload := range report.Changed {
+		fmt.Printf("changed (%s): %s %s\n", action, upload.Id, upload.Title)
+	}
+	for _, id := range report.MissingTranscripts {
+		fmt.Printf("no transcript found: %s\n", id)
+	}
+}
+
+// runSettings applies the index settings the search handlers rely on
+func (cfg *Config) runSettings(args []string) error {
+	flags := flag.NewFlagSet("settings", flag.ContinueOnError)
+	indexName := flags.String("index", "videos", "name of the meilisearch index to configure")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	dimensions := 0
+	if cfg.embedder != nil {
+		dimensions = cfg.embedder.Dimensions()
+	}
+	index := ingest.NewMeiliIndex(cfg.searchClient, *indexName)
+	err = index.ApplySettings(context.Background(), ingest.Settings(dimensions))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("settings applied to index %s\n", *indexName)
+	return nil
+}
+
+// runEmbed computes the passage vectors of videos ingested before semantic
+// search was configured
+func (cfg *Config) runEmbed(args []string) error {
+	flags := flag.NewFlagSet("embed", flag.ContinueOnError)
+	indexName := flags.String("index", "videos", "name of the meilisearch index to embed")
+	all := flags.Bool("all", false, "embed every video again, for example after changing the word vectors")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if cfg.embedder == nil {
+		return errors.New("EMBEDDINGS_FILE is not set")
+	}
+	index := ingest.NewMeiliIndex(cfg.searchClient, *indexName)
+	embedded, err := ingest.Embed(context.Background(), index, cfg.embedder, *all)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d videos embedded in index %s\n", embedded, *indexName)
+	return nil
+}
+
+// runRelated computes the related videos of every video in the index, sync
+// does this after ingesting new videos
+func (cfg *Config) runRelated(args []string) error {
+	flags := flag.NewFlagSet("related", flag.ContinueOnError)
+	indexName := flags.String("index", "videos", "name of the meilisearch index to compute related videos for")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	index := ingest.NewMeiliIndex(cfg.searchClient, *indexName)
+	updated, err := ingest.Relate(context.Background(), index)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("related videos updated for %d videos in index %s\n", updated, *indexName)
+	return nil
+}
+
+// runTopics extracts the topics of every video in the index, sync does this
+// after ingesting new videos
+func (cfg *Config) runTopics(args []string) error {
+	flags := flag.NewFlagSet("topics", flag.ContinueOnError)
+	indexName := flags.String("index", "videos", "name of the meilisearch index to extract topics for")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	index := ingest.NewMeiliIndex(cfg.searchClient, *indexName)
+	updated, err := ingest.ExtractTopics(context.Background(), index)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("topics updated for %d videos in index %s\n", updated, *indexName)
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/feed"
+	"github.com/bevane/safina-society-search/internal/ingest"
+	"github.com/bevane/safina-society-search/internal/link"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// number of videos listed by a search feed
+const feedEntries = 20
+
+// feedEntry is a video of a search feed with the passage the query matched
+type feedEntry struct {
+	hit        model.FormattedVideoHit
+	collection model.Collection
+	published  time.Time
+}
+
+// handlerSearchFeed serves an Atom feed of the newest videos matching the
+// search, each entry links to the moment of the video the query best
+// matched so that feed readers can follow new videos about a subject
+func (cfg *Config) handlerSearchFeed(w http.ResponseWriter, r *http.Request) {
+	collection, ok := cfg.collectionFromRequest(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	state := model.SearchStateFromValues(r.URL.Query())
+	state.Lang = searchLanguage(state.Lang)
+	state.Topic = searchTopic(state.Topic)
+	// same minimum length as the search page
+	if (state.Query == "" && state.Topic == "") || (state.Query != "" && len(state.Query) <= 2) {
+		http.Error(w, "query must be longer than 2 characters", http.StatusBadRequest)
+		return
+	}
+	if _, ok := parseVideoQuery(state.Query); ok {
+		http.Error(w, "searches within a video have no feed", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := getFeedEntries(r.Context(), cfg.searchedCollections(collection), state, cfg.searchClient)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to get feed entries", slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	meta := cfg.searchMeta(collection, state, model.Results{})
+	selfUrl := cfg.siteUrl + collection.FeedUrl(state)
+	searchFeed := feed.Feed{
+		Id:           selfUrl,
+		Updated:      time.Unix(0, 0),
+		Title:        meta.Title,
+		SelfUrl:      selfUrl,
+		AlternateUrl: meta.Url,
+		Author:       collection.ChannelName,
+	}
+	for _, entry := range entries {
+		feedEntry := feedEntryOf(r.Context(), entry, state)
+		searchFeed.Entries = append(searchFeed.Entries, feedEntry)
+		if feedEntry.Updated.After(searchFeed.Updated) {
+			searchFeed.Updated = feedEntry.Updated
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	err = feed.Write(w, searchFeed)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write feed", slog.Any("error", err))
+	}
+}
+
+// getFeedEntries returns the newest videos matching the search in the
+// collections, newest first
+func getFeedEntries(ctx context.Context, collections []model.Collection, state model.SearchState, searchClient meilisearch.ServiceManager) ([]feedEntry, error) {
+	transcriptField := model.TranscriptField(state.Lang)
+	searchRequest := meilisearch.SearchRequest{
+		AttributesToSearchOn: []string{"title", transcriptField},
+		AttributesToRetrieve: []string{"id", "title", "uploadDate", transcriptField},
+		ShowMatchesPosition:  true,
+		Sort:                 []string{"uploadDate:desc"},
+		// the ranking rules only sort videos that match equally well, so
+		// only videos matching every word are listed rather than mixing in
+		// videos that match fewer of them
+		MatchingStrategy: meilisearch.All,
+		Limit:            feedEntries,
+	}
+	filterSearch(&searchRequest, state)
+
+	responses := make([]model.SearchResponseVideos, len(collections))
+	errs := make([]error, len(collections))
+	var wg sync.WaitGroup
+	for i, collection := range collections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// the client modifies the request, so each search gets a copy
+			request := searchRequest
+			responses[i], errs[i] = searchIndex(ctx, collection.IndexName, state.Query, &request, searchClient)
+		}()
+	}
+	wg.Wait()
+	err := errors.Join(errs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []feedEntry
+	for i, response := range responses {
+		for _, hit := range response.Hits {
+			published, _ := time.Parse(ingest.UploadDateLayout, hit.UploadDate)
+			entries = append(entries, feedEntry{hit: hit, collection: collections[i], published: published})
+		}
+	}
+	// videos without an upload date are listed last
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].published.After(entries[j].published)
+	})
+	return entries[:min(len(entries), feedEntries)], nil
+}
+
+// feedEntryOf returns the entry of a video linking to the moment of its best
+// passage, or to its start if only the title matched
+func feedEntryOf(ctx context.Context, entry feedEntry, state model.SearchState) feed.Entry {
+	hit := entry.hit
+	video, snippet, ok := bestMoment(ctx, hit, state.Lang)
+	var content strings.Builder
+	if ok {
+		fmt.Fprintf(&content, "<p>[%s] %s</p>", snippet.Timestamp, snippetHTML(snippet.Text))
+	} else if excerpt := passageSnippet(hit.TranscriptIn(state.Lang), 0, snippetWords); excerpt != "" {
+		fmt.Fprintf(&content, "<p>%s</p>", html.EscapeString(excerpt))
+	}
+	fmt.Fprintf(&content, `<p><img src="%s" alt=""></p>`, html.EscapeString(link.Thumbnail(hit.Id)))
+	// a feed reader needs a date for every entry, the epoch stands for an
+	// unknown upload date
+	updated := entry.published
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+	return feed.Entry{
+		// the id of an entry must not change when its passage does
+		Id:          link.Video{Id: hit.Id}.Watch(),
+		Title:       hit.Title,
+		Url:         video.Watch(),
+		Updated:     updated,
+		ContentHTML: content.String(),
+	}
+}
+
+// bestMoment returns the link to the moment of the passage of the video
+// the query best matched and the passage, or the link to the start of the
+// video and false if the transcript has no match e.g. when only the title
+// matched
+func bestMoment(ctx context.Context, hit model.FormattedVideoHit, lang string) (link.Video, model.Snippet, bool) {
+	video := link.Video{Id: hit.Id, Captions: lang}
+	snippets := rankedPassages(ctx, hit.TranscriptIn(lang), hit.MatchesPosition.TranscriptIn(lang), lang, hit.Id)
+	if len(snippets) == 0 {
+		return video, model.Snippet{}, false
+	}
+	video.Start, _ = strconv.Atoi(snippets[0].TimestampSeconds)
+	return video, snippets[0], true
+}
+
+// snippetHTML returns the text escaped with the matches marked
+func snippetHTML(text model.HighlightedText) string {
+	var sb strings.Builder
+	for _, part := range text {
+		if part.Marked {
+			sb.WriteString("<mark>" + html.EscapeString(part.Text) + "</mark>")
+		} else {
+			sb.WriteString(html.EscapeString(part.Text))
+		}
+	}
+	return sb.String()
+}
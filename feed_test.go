@@ -0,0 +1,128 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bevane/safina-society-search/internal/ingest"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+type rankedVideo struct {
+	id         string
+	title      string
+	transcript string
+	uploadDate string
+}
+
+// rankingMeilisearch searches the videos of each index ranking them with
+// the sort, words and attribute rules in the order of the index settings,
+// the other rules are left out
+func rankingMeilisearch(t *testing.T, indexes map[string][]rankedVideo) meilisearch.ServiceManager {
+	t.Helper()
+	rules := ingest.Settings(0).RankingRules
+	return fakeMeilisearch(t, func(w http.ResponseWriter, r *http.Request) {
+		index, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/indexes/"), "/search")
+		if !ok {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		var body meilisearch.SearchRequest
+		decodeBody(t, r, &body)
+		terms := strings.Fields(strings.ToLower(body.Query))
+		words := func(v rankedVideo) int {
+			text := strings.ToLower(v.title + " " + v.transcript)
+			n := 0
+			for _, term := range terms {
+				if strings.Contains(text, term) {
+					n++
+				}
+			}
+			return n
+		}
+		inTitle := func(v rankedVideo) int {
+			return strings.Count(strings.ToLower(v.title), terms[0])
+		}
+		var hits []rankedVideo
+		for _, v := range indexes[index] {
+			matched := words(v)
+			if matched == 0 || (body.MatchingStrategy == meilisearch.All && matched < len(terms)) {
+				continue
+			}
+			hits = append(hits, v)
+		}
+		sorted := slices.Contains(body.Sort, "uploadDate:desc")
+		slices.SortStableFunc(hits, func(a, b rankedVideo) int {
+			for _, rule := range rules {
+				c := 0
+				switch {
+				case rule == "sort" && sorted:
+					c = strings.Compare(b.uploadDate, a.uploadDate)
+				case rule == "words":
+					c = cmp.Compare(words(b), words(a))
+				case rule == "attribute":
+					c = cmp.Compare(inTitle(b), inTitle(a))
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return 0
+		})
+		var response []map[string]any
+		for _, v := range hits[:min(len(hits), int(body.Limit))] {
+			response = append(response, map[string]any{"id": v.id, "title": v.title, "transcript": v.transcript, "uploadDate": v.uploadDate})
+		}
+		writeJSON(w, map[string]any{"hits": response})
+	})
+}
+
+func TestGetFeedEntriesNewestFirst(t *testing.T) {
+	// more videos match every word than the feed lists
+	var safina []rankedVideo
+	for i := range feedEntries + 5 {
+		safina = append(safina, rankedVideo{
+			id:         fmt.Sprintf("match%06d", i),
+			title:      "Patience and gratitude",
+			transcript: "patience gratitude",
+			uploadDate: fmt.Sprintf("20%02d0101", i),
+		})
+	}
+	// the newest video only matches one of the words
+	safina = append(safina, rankedVideo{id: "partial0001", title: "Patience", transcript: "patience", uploadDate: "20500101"})
+	client := rankingMeilisearch(t, map[string][]rankedVideo{
+		"safina": safina,
+		"other":  {{id: "other000001", title: "Patience and gratitude", transcript: "patience gratitude", uploadDate: "20220601"}},
+	})
+	collections := []model.Collection{
+		{Id: "safina", IndexName: "safina"},
+		{Id: "other", IndexName: "other"},
+	}
+	entries, err := getFeedEntries(context.Background(), collections, model.SearchState{Query: "patience gratitude"}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != feedEntries {
+		t.Fatalf("feed lists %d videos, want %d", len(entries), feedEntries)
+	}
+	var got []string
+	for _, entry := range entries[:4] {
+		got = append(got, entry.hit.Id)
+	}
+	// the newest videos of both collections matching every word come first
+	if want := []string{"match000024", "match000023", "other000001", "match000022"}; !slices.Equal(got, want) {
+		t.Errorf("feed starts with %q, want %q", got, want)
+	}
+	for _, entry := range entries {
+		if entry.hit.Id == "partial0001" {
+			t.Error("feed lists a video matching only one of the words")
+		}
+	}
+}
@@ -1,42 +1,78 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
-	"unicode"
 
+	"github.com/bevane/safina-society-search/internal/analytics"
+	"github.com/bevane/safina-society-search/internal/embedding"
+	"github.com/bevane/safina-society-search/internal/ingest"
+	"github.com/bevane/safina-society-search/internal/link"
 	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/transcript"
 	"github.com/bevane/safina-society-search/internal/views"
 	"github.com/meilisearch/meilisearch-go"
 )
 
+const hitsPerPage = 10
+
+// pages of results that can be paged through
+const maxPages = ingest.MaxTotalHits / hitsPerPage
+
+// number of words in the snippet of each result
+const snippetWords = 70
+
+// topics are single words as split by embedding.Tokenize
+var topicRegex = regexp.MustCompile(`^[\p{L}\p{N}']{1,50}$`)
+
 func (cfg *Config) handlerSearch(w http.ResponseWriter, r *http.Request) {
+	collection, ok := cfg.collectionFromRequest(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
 	params := r.URL.Query()
-	query := params.Get("q")
-	page := params.Get("page")
-	pageNumber, err := strconv.Atoi(page)
+	page := params.Get(model.ParamPage)
+	state := model.SearchStateFromValues(params)
+	state.Lang = searchLanguage(state.Lang)
+	state.Mode = cfg.searchMode(state.Mode, collection)
+	state.Topic = searchTopic(state.Topic)
+	pageNumber := state.Page
+	query := state.Query
 	isHTMX := r.Header.Get("Hx-Request") != ""
-	slog.Info(fmt.Sprintf("GET /search: isHTMX: %v, params: %v", isHTMX, params))
+	// previews of the page before the results are known
+	meta := cfg.searchMeta(collection, state, model.Results{})
+	slog.InfoContext(r.Context(), "search",
+		slog.Bool("isHTMX", isHTMX),
+		slog.String("collection", collection.Id),
+		slog.String("query", query),
+		slog.String("lang", state.Lang),
+		slog.String("mode", state.Mode),
+		slog.String("topic", state.Topic),
+		slog.String("page", page),
+	)
 
-	// if the user clears the search input, show the quickstart section again
-	if len(query) == 0 {
+	// if the user clears the search input, show the quickstart section again.
+	// Without a query all videos about the topic are listed
+	if len(query) == 0 && state.Topic == "" {
 		if isHTMX {
-			quickStartComponent := views.QuickStart()
+			quickStartComponent := views.QuickStart(collection)
 			err := quickStartComponent.Render(r.Context(), w)
 			if err != nil {
-				slog.Error("unable to render error component", slog.Any("error", err))
+				slog.ErrorContext(r.Context(), "unable to render error component", slog.Any("error", err))
 			}
 		} else {
-			err := views.Index(query, nil).Render(r.Context(), w)
+			err := views.Index(collection, state, nil, meta).Render(r.Context(), w)
 			if err != nil {
-				slog.Error("unable to render full html for empty query", slog.Any("error", err))
+				slog.ErrorContext(r.Context(), "unable to render full html for empty query", slog.Any("error", err))
 			}
 		}
 		return
@@ -46,161 +82,508 @@ func (cfg *Config) handlerSearch(w http.ResponseWriter, r *http.Request) {
 	// there are cases where a user might want to actually search for 2 char words
 	// for example 'AI', if the user wraps it in quotes it will work and also
 	// give the results they want instead of returning results with words like m[ai]n
-	if len(query) <= 2 {
+	if len(query) > 0 && len(query) <= 2 {
 		errComponent := views.InsufficientInput()
 		if isHTMX {
 			err := errComponent.Render(r.Context(), w)
 			if err != nil {
-				slog.Error("unable to render error component", slog.Any("error", err))
+				slog.ErrorContext(r.Context(), "unable to render error component", slog.Any("error", err))
 			}
 		} else {
-			err := views.Index(query, errComponent).Render(r.Context(), w)
+			err := views.Index(collection, state, errComponent, meta).Render(r.Context(), w)
 			if err != nil {
-				slog.Error("unable to render full html with error", slog.Any("error", err))
+				slog.ErrorContext(r.Context(), "unable to render full html with error", slog.Any("error", err))
 			}
 		}
 		return
 	}
 
 	// prevents the user from getting an invalid page by editing the url
-	// the max number of pages must coincide with the maxTotalHits (configured in meilisearch instance)
-	// divided by HitsPerPage (defined in search request sent to meiliesearch)
-	if err != nil || pageNumber < 1 || pageNumber > 5 {
-		errComponent := views.BadRequestPageNumber()
+	if pageNumber < 1 || pageNumber > maxPages {
+		errComponent := views.BadRequestPageNumber(maxPages)
 		if isHTMX {
-			err = errComponent.Render(r.Context(), w)
+			err := errComponent.Render(r.Context(), w)
 			if err != nil {
-				slog.Error("unable to render error component", slog.Any("error", err))
+				slog.ErrorContext(r.Context(), "unable to render error component", slog.Any("error", err))
 			}
 		} else {
-			err = views.Index(query, errComponent).Render(r.Context(), w)
+			err := views.Index(collection, state, errComponent, meta).Render(r.Context(), w)
 			if err != nil {
-				slog.Error("unable to render full html with error", slog.Any("error", err))
+				slog.ErrorContext(r.Context(), "unable to render full html with error", slog.Any("error", err))
 			}
 		}
 		return
 	}
 
-	results, totalPages, err := getSearchResults(query, pageNumber, cfg.searchClient)
+	searchStart := time.Now()
+	// fall back to keyword search if none of the words are known
+	var queryVector []float32
+	if state.Mode == model.SearchModeHybrid {
+		queryVector = cfg.embedder.Embed(query)
+	}
+	var results model.Results
+	var totalPages int
+	var err error
+	// id:<video id> searches within a single video, optionally within a
+	// time range of it
+	if vq, ok := parseVideoQuery(query); ok {
+		results, err = cfg.getVideoQueryResults(r.Context(), cfg.searchedCollections(collection), vq, state.Lang)
+		totalPages = 1
+	} else {
+		results, totalPages, err = getSearchResults(r.Context(), cfg.searchedCollections(collection), state, queryVector, cfg.searchClient)
+		results.FeedUrl = collection.FeedUrl(state)
+		if cfg.alerts != nil && cfg.mailer != nil {
+			results.AlertPath = collection.AlertsPath()
+		}
+	}
 	if err != nil {
-		errComponent := views.InternalError()
+		errComponent := views.InternalError(requestIDFromContext(r.Context()))
 		if isHTMX {
 			err = errComponent.Render(r.Context(), w)
 			if err != nil {
-				slog.Error("unable to render error component", slog.Any("error", err))
+				slog.ErrorContext(r.Context(), "unable to render error component", slog.Any("error", err))
 			}
 		} else {
-			err = views.Index(query, errComponent).Render(r.Context(), w)
+			err = views.Index(collection, state, errComponent, meta).Render(r.Context(), w)
 			if err != nil {
-				slog.Error("unable to render full html with error", slog.Any("error", err))
+				slog.ErrorContext(r.Context(), "unable to render full html with error", slog.Any("error", err))
 			}
 		}
 		return
 	}
 
-	resultsComponent := views.Results(results, totalPages, pageNumber, query)
+	for i, item := range results.Items {
+		position := (pageNumber-1)*hitsPerPage + i + 1
+		results.Items[i].PlayerUrl = playerUrl(state, item.VideoId, item.TimestampSeconds, position)
+		if cfg.bookmarks != nil {
+			results.Items[i].SaveUrl = saveMomentPath(item.VideoId, item.TimestampSeconds, item.Language)
+		}
+		for j, snippet := range item.Snippets {
+			results.Items[i].Snippets[j].PlayerUrl = playerUrl(state, item.VideoId, snippet.TimestampSeconds, position)
+		}
+	}
+	if cfg.analytics != nil {
+		cfg.recordSearch(r.Context(), collection, state, results.TotalHits, time.Since(searchStart))
+		// route result links through the click endpoint so that click
+		// through can be recorded before redirecting to youtube
+		for i, item := range results.Items {
+			position := (pageNumber-1)*hitsPerPage + i + 1
+			results.Items[i].Url = clickUrl(state, item.VideoId, item.TimestampSeconds, position)
+			for j, snippet := range item.Snippets {
+				results.Items[i].Snippets[j].Url = clickUrl(state, item.VideoId, snippet.TimestampSeconds, position)
+			}
+		}
+	}
+
+	resultsComponent := views.Results(collection, results, totalPages, state)
+	// the next page of infinite scroll replaces the item that loaded it
+	if r.Header.Get("Hx-Target") == views.LoadMoreId {
+		resultsComponent = views.MoreResults(collection, results, totalPages, state)
+	}
 	if isHTMX {
 		err = resultsComponent.Render(r.Context(), w)
 		if err != nil {
-			slog.Error("unable to render result component", slog.Any("error", err))
+			slog.ErrorContext(r.Context(), "unable to render result component", slog.Any("error", err))
 		}
 	} else {
-		err = views.Index(query, resultsComponent).Render(r.Context(), w)
+		err = views.Index(collection, state, resultsComponent, cfg.searchMeta(collection, state, results)).Render(r.Context(), w)
 		if err != nil {
-			slog.Error("unable to full html with results", slog.Any("error", err))
+			slog.ErrorContext(r.Context(), "unable to full html with results", slog.Any("error", err))
 		}
 	}
 }
 
-func getSearchResults(query string, page int, searchClient meilisearch.ServiceManager) (model.Results, int, error) {
-	resRaw, err := searchClient.Index("videos").SearchRaw(query, &meilisearch.SearchRequest{
-		// crop to show a snippet for each search result
-		AttributesToCrop:      []string{"transcript"},
-		CropLength:            70,
-		AttributesToHighlight: []string{"title", "transcript"},
-		HighlightPreTag:       "<mark>",
-		HighlightPostTag:      "</mark>",
-		ShowMatchesPosition:   true,
-		Page:                  int64(page),
-		HitsPerPage:           10,
+func (cfg *Config) handlerCollection(w http.ResponseWriter, r *http.Request) {
+	collection, ok := cfg.collectionFromRequest(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	err := views.Index(collection, model.SearchState{}, nil, cfg.pageMeta(collection, collection.HomePath())).Render(r.Context(), w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to render collection home page", slog.Any("error", err))
+	}
+}
+
+func (cfg *Config) handlerClick(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	videoId := params.Get("v")
+	timestampSeconds := params.Get("t")
+	// only redirect to urls built from a valid video id and timestamp so the
+	// endpoint can not be used as an open redirect
+	if !link.ValidVideoId(videoId) {
+		http.Error(w, "invalid video id", http.StatusBadRequest)
+		return
+	}
+	if _, err := strconv.Atoi(timestampSeconds); timestampSeconds != "" && err != nil {
+		http.Error(w, "invalid timestamp", http.StatusBadRequest)
+		return
+	}
+	state := resultState(params)
+	position, _ := strconv.Atoi(params.Get("pos"))
+	cfg.recordClick(r.Context(), state, videoId, position)
+	http.Redirect(w, r, videoUrl(r.Context(), videoId, timestampSeconds, state.Lang), http.StatusFound)
+}
+
+// resultState returns the state of the search a result link was clicked
+// on from the params added by resultParams
+func resultState(params url.Values) model.SearchState {
+	state := model.SearchStateFromValues(params)
+	state.Lang = searchLanguage(state.Lang)
+	state.Topic = searchTopic(state.Topic)
+	if state.Mode != model.SearchModeHybrid {
+		state.Mode = ""
+	}
+	// the page is not part of the state of a result
+	state.Page = 0
+	return state
+}
+
+func (cfg *Config) recordClick(ctx context.Context, state model.SearchState, videoId string, position int) {
+	// browsing a topic without a query is not a search for the report of
+	// the top queries
+	if cfg.analytics == nil || analytics.NormalizeQuery(state.Query) == "" {
+		return
+	}
+	err := cfg.analytics.Record(analytics.Event{
+		Type:     analytics.EventClick,
+		Query:    state.Query,
+		Filters:  searchFilters(state),
+		VideoId:  videoId,
+		Position: position,
 	})
 	if err != nil {
-		slog.Error("unable to get search results from meilisearch", slog.Any("error", err))
-		return model.Results{}, 0, err
+		slog.ErrorContext(ctx, "unable to record click", slog.Any("error", err))
 	}
+}
 
-	searchResponse := model.SearchResponseVideos{}
-	err = json.Unmarshal(*resRaw, &searchResponse)
+func (cfg *Config) recordSearch(ctx context.Context, collection model.Collection, state model.SearchState, resultCount int, latency time.Duration) {
+	if analytics.NormalizeQuery(state.Query) == "" {
+		return
+	}
+	filters := searchFilters(state)
+	if collection.Id != cfg.collections[0].Id {
+		if filters == nil {
+			filters = map[string]string{}
+		}
+		filters["collection"] = collection.Id
+	}
+	err := cfg.analytics.Record(analytics.Event{
+		Type:        analytics.EventSearch,
+		Query:       state.Query,
+		Filters:     filters,
+		ResultCount: resultCount,
+		Page:        state.Page,
+		LatencyMs:   latency.Milliseconds(),
+	})
 	if err != nil {
-		slog.Error("unable to unmarshal search results from meilisearch", slog.Any("error", err))
+		slog.ErrorContext(ctx, "unable to record search", slog.Any("error", err))
 	}
-	results := model.Results{
-		Items: make([]model.Result, len(searchResponse.Hits)),
+}
+
+// searchLanguage returns the language code to search in or "" to search
+// the transcripts in the default language
+func searchLanguage(code string) string {
+	if code == model.DefaultLanguage {
+		return ""
 	}
-	for i, hit := range searchResponse.Hits {
-		// will get the left most timestamp in the snippet
-		timestampSeconds, err := getTimestampSeconds(hit.Formatted.Transcript)
-		if err != nil {
-			slog.Error("unable to get timestamp in from transcript", slog.Any("error", err))
+	if _, ok := model.LanguageByCode(code); !ok {
+		return ""
+	}
+	return code
+}
+
+// searchMode returns SearchModeHybrid if it is requested and the
+// collection can be searched by meaning, otherwise "" for keyword search
+func (cfg *Config) searchMode(mode string, collection model.Collection) string {
+	if mode != model.SearchModeHybrid || !collection.Semantic || cfg.embedder == nil {
+		return ""
+	}
+	return mode
+}
+
+// searchTopic returns the topic to filter by or "" if it is not a valid
+// topic
+func searchTopic(topic string) string {
+	if !topicRegex.MatchString(topic) {
+		return ""
+	}
+	return topic
+}
+
+func searchFilters(state model.SearchState) map[string]string {
+	filters := map[string]string{}
+	if state.Lang != "" {
+		filters["lang"] = state.Lang
+	}
+	if state.Mode != "" {
+		filters["mode"] = state.Mode
+	}
+	if state.Topic != "" {
+		filters["topic"] = state.Topic
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return filters
+}
+
+// weight of semantic search relative to keyword search in hybrid search
+const semanticRatio = 0.5
+
+// getSearchResults searches the indexes of the collections. When more than
+// one collection is searched, the indexes are searched at once by a
+// federated search which pages through the merged hits, and the hits are
+// labeled with the channel they are from. If a query vector is given,
+// keyword and semantic results are fused by meilisearch
+func getSearchResults(ctx context.Context, collections []model.Collection, state model.SearchState, queryVector []float32, searchClient meilisearch.ServiceManager) (model.Results, int, error) {
+	query := state.Query
+	lang := state.Lang
+	page := state.Page
+	transcriptField := model.TranscriptField(lang)
+	searchRequest := meilisearch.SearchRequest{
+		// id is searched so that users can search within a specific video
+		AttributesToSearchOn: []string{"id", "title", transcriptField},
+		// only the transcript searched is needed for the snippets, the
+		// other transcripts are left out of the response
+		AttributesToRetrieve: []string{"id", "title", "uploadDate", transcriptField, "vectorStarts"},
+		// snippets are highlighted from the positions of the matches
+		// rather than by meilisearch, which does not escape the text
+		ShowMatchesPosition: true,
+	}
+	filterSearch(&searchRequest, state)
+	if queryVector != nil {
+		searchRequest.Vector = queryVector
+		searchRequest.Hybrid = &meilisearch.SearchRequestHybrid{
+			Embedder:      embedding.EmbedderName,
+			SemanticRatio: semanticRatio,
+		}
+		// the passage vectors are used to link to the passage closest to
+		// the query, they embed the transcript in the default language
+		searchRequest.RetrieveVectors = lang == ""
+	}
+
+	var searchResponse model.SearchResponseVideos
+	var err error
+	if len(collections) == 1 {
+		searchRequest.Page = int64(page)
+		searchRequest.HitsPerPage = hitsPerPage
+		searchResponse, err = searchIndex(ctx, collections[0].IndexName, query, &searchRequest, searchClient)
+	} else {
+		searchResponse, err = searchIndexes(ctx, collections, query, searchRequest, page, searchClient)
+	}
+	if err != nil {
+		return model.Results{}, 0, err
+	}
+	channelNames := map[string]string{}
+	for _, collection := range collections {
+		channelNames[collection.IndexName] = collection.ChannelName
+	}
+	results := model.Results{TotalHits: int(searchResponse.TotalHits)}
+	totalPages := int(searchResponse.TotalPages)
+	hits := searchResponse.Hits
+
+	results.Items = make([]model.Result, len(hits))
+	for i, hit := range hits {
+		matches := hit.MatchesPosition.TranscriptIn(lang)
+		snippets := rankedPassages(ctx, hit.TranscriptIn(lang), matches, lang, hit.Id)
+		// link to the cue of the best passage, or to the start of the
+		// video if the transcript has no match e.g. when only the title
+		// matched
+		timestampSeconds := "0"
+		if len(snippets) > 0 {
+			timestampSeconds = snippets[0].TimestampSeconds
+		}
+		matchesCount := len(matches)
+		// a hit found by meaning alone has no match to crop the snippet
+		// around, show the passage closest to the query instead
+		related := false
+		// otherwise show the start of the transcript
+		snippetStart := 0
+		if queryVector != nil && matchesCount == 0 && lang == "" {
+			if start, ok := nearestPassageStart(queryVector, hit); ok {
+				snippetStart = start
+				timestampSeconds = strconv.Itoa(start)
+				related = true
+			}
+		}
+		var snippet model.HighlightedText
+		if len(snippets) == 0 {
+			snippet = model.PlainText(passageSnippet(hit.TranscriptIn(lang), snippetStart, snippetWords))
 		}
-		// remove timestamps and anything that are not subtitles from
-		// the snippet
-		cleanedSnippet := cleanSnippet(hit.Formatted.Transcript)
 		results.Items[i] = model.Result{
-			Title: hit.Formatted.Title,
-			// construct url linking to timestamp of the crop/snippet
-			Url:          fmt.Sprintf("https://youtu.be/%s&t=%s", hit.Id, timestampSeconds),
-			ThumbnailUrl: fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", hit.Id),
-			Snippet:      cleanedSnippet,
+			VideoId: hit.Id,
+			Title:   model.Highlight(hit.Title, hit.MatchesPosition.Title),
+			// construct url linking to the timestamp of the best passage
+			Url:              videoUrl(ctx, hit.Id, timestampSeconds, lang),
+			TimestampSeconds: timestampSeconds,
+			ThumbnailUrl:     link.Thumbnail(hit.Id),
+			Snippet:          snippet,
+			Snippets:         snippets,
 			// number of occurences of search term in the video
-			MatchesCount: len(hit.MatchesPosition.Transcript),
+			MatchesCount: matchesCount,
+			Related:      related,
+			Language:     lang,
+		}
+		if len(collections) > 1 && hit.Federation != nil {
+			results.Items[i].ChannelName = channelNames[hit.Federation.IndexUid]
 		}
 	}
-	return results, int(searchResponse.TotalPages), nil
+	return results, totalPages, nil
 }
 
-func getTimestampSeconds(text string) (string, error) {
-	if text == "" {
-		return "", errors.New("error getting timestamp: text is empty")
+// filterSearch restricts the search to the videos in the language and
+// about the topic of the search state, and to those matching the extra
+// filters
+func filterSearch(searchRequest *meilisearch.SearchRequest, state model.SearchState, extra ...string) {
+	filters := extra
+	if state.Lang != "" {
+		language, _ := model.LanguageByCode(state.Lang)
+		filters = append(filters, fmt.Sprintf("languages = %s", state.Lang))
+		// use the tokenizer of the language instead of detecting it
+		searchRequest.Locates = []string{language.Locale}
 	}
-	var timestampStr string
-	// regex to match srt timing "00:20:30,50 -->" and capture the time only
-	r, _ := regexp.Compile(`(\d{2}:\d{2}:\d{2}),\d{3} -->`)
-	if !r.MatchString(text) {
-		return "", errors.New("no timestamp found")
+	if state.Topic != "" {
+		filters = append(filters, fmt.Sprintf("topics = \"%s\"", state.Topic))
 	}
-	timestampStr = r.FindStringSubmatch(text)[1]
-	timestamp, err := time.Parse(time.TimeOnly, timestampStr)
-	if err != nil {
-		return "", err
+	if len(filters) > 0 {
+		searchRequest.Filter = strings.Join(filters, " AND ")
+	}
+}
+
+// idFilter returns the filter matching the videos with the given ids, ""
+// if none of the ids is valid. Ids are quoted in the filter, so only valid
+// ids are kept
+func idFilter(ids []string) string {
+	var quoted []string
+	for _, id := range ids {
+		if link.ValidVideoId(id) {
+			quoted = append(quoted, `"`+id+`"`)
+		}
+	}
+	if len(quoted) == 0 {
+		return ""
 	}
-	reference, _ := time.Parse(time.TimeOnly, "00:00:00")
-	// timestamp is an absolute time, so subtract reference from it to get
-	// a delta time which can be converted into seconds
-	timestampSeconds := timestamp.Sub(reference).Seconds()
-	return strconv.Itoa(int(timestampSeconds)), nil
+	return fmt.Sprintf("id IN [%s]", strings.Join(quoted, ", "))
 }
 
-func cleanSnippet(text string) string {
+// nearestPassageStart returns the start in seconds of the passage of the
+// hit whose vector is closest to the query vector
+func nearestPassageStart(queryVector []float32, hit model.FormattedVideoHit) (int, bool) {
+	vectors, ok := hit.Vectors[embedding.EmbedderName]
+	if !ok {
+		return 0, false
+	}
+	nearest := embedding.Nearest(queryVector, vectors.Embeddings)
+	if nearest < 0 || nearest >= len(hit.VectorStarts) {
+		return 0, false
+	}
+	return hit.VectorStarts[nearest], true
+}
+
+// passageSnippet returns the text of the cues of the transcript from the
+// start of the passage, about maxWords long
+func passageSnippet(srt string, startSeconds int, maxWords int) string {
+	cues, err := transcript.ParseSRT(strings.NewReader(srt))
+	if err != nil {
+		return ""
+	}
 	var sb strings.Builder
-	for i, char := range text {
-		// skip the '>' in '-->'
-		if i > 0 && char == '>' && text[i-1] == '-' {
+	words := 0
+	for _, cue := range cues {
+		if int(cue.Start.Seconds()) < startSeconds {
 			continue
 		}
-		// skip the second '-' in '-->'
-		if i > 0 && char == '-' && text[i-1] == '-' {
-			continue
+		if sb.Len() > 0 {
+			sb.WriteString(" ")
 		}
-		// skip the first '-' in '-->'
-		if i > 0 && char == '-' && text[i-1] == ' ' {
-			continue
+		sb.WriteString(cue.Text)
+		words += len(strings.Fields(cue.Text))
+		if words >= maxWords {
+			break
 		}
-		if unicode.IsNumber(char) || char == ':' || char == ',' {
-			continue
-		}
-		// write characters that were not skipped into new string
-		sb.WriteRune(char)
 	}
 	return sb.String()
 }
+
+func searchIndex(ctx context.Context, indexName string, query string, searchRequest *meilisearch.SearchRequest, searchClient meilisearch.ServiceManager) (model.SearchResponseVideos, error) {
+	resRaw, err := searchClient.Index(indexName).SearchRawWithContext(ctx, query, searchRequest)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to get search results from meilisearch", slog.String("index", indexName), slog.Any("error", err))
+		return model.SearchResponseVideos{}, err
+	}
+
+	searchResponse := model.SearchResponseVideos{}
+	err = json.Unmarshal(*resRaw, &searchResponse)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to unmarshal search results from meilisearch", slog.String("index", indexName), slog.Any("error", err))
+	}
+	return searchResponse, nil
+}
+
+// searchIndexes searches the indexes of the collections with a federated
+// search, the merged hits are paged through like the hits of a single index
+func searchIndexes(ctx context.Context, collections []model.Collection, query string, searchRequest meilisearch.SearchRequest, page int, searchClient meilisearch.ServiceManager) (model.SearchResponseVideos, error) {
+	multiSearch := &meilisearch.MultiSearchRequest{
+		Federation: &meilisearch.MultiSearchFederation{
+			Offset: int64((page - 1) * hitsPerPage),
+			Limit:  hitsPerPage,
+		},
+	}
+	for _, collection := range collections {
+		request := searchRequest
+		request.IndexUID = collection.IndexName
+		request.Query = query
+		multiSearch.Queries = append(multiSearch.Queries, &request)
+	}
+	res, err := searchClient.MultiSearchWithContext(ctx, multiSearch)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to get federated search results from meilisearch", slog.Any("error", err))
+		return model.SearchResponseVideos{}, err
+	}
+
+	// the client decodes the hits into maps, decode them again as videos
+	hitsRaw, err := json.Marshal(res.Hits)
+	if err != nil {
+		return model.SearchResponseVideos{}, err
+	}
+	searchResponse := model.SearchResponseVideos{TotalHits: res.EstimatedTotalHits}
+	err = json.Unmarshal(hitsRaw, &searchResponse.Hits)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to unmarshal federated search results from meilisearch", slog.Any("error", err))
+		return model.SearchResponseVideos{}, err
+	}
+	pages := (res.EstimatedTotalHits + hitsPerPage - 1) / hitsPerPage
+	searchResponse.TotalPages = min(pages, maxPages)
+	return searchResponse, nil
+}
+
+// videoUrl links to the timestamp in the video, if lang is set the
+// subtitles in that language are turned on. The privacy enhanced player is
+// linked to if the user prefers it
+func videoUrl(ctx context.Context, videoId string, timestampSeconds string, lang string) string {
+	start, _ := strconv.Atoi(timestampSeconds)
+	return link.For(ctx, link.Video{Id: videoId, Start: start, Captions: lang})
+}
+
+// clickUrl wraps a result link with the click endpoint, position is the
+// 1-based rank of the result across all pages
+func clickUrl(state model.SearchState, videoId string, timestampSeconds string, position int) string {
+	return "/click?" + resultParams(state, videoId, timestampSeconds, position).Encode()
+}
+
+// playerUrl loads the video at the timestamp in the player on the search
+// page, position is 0 when the link is not a search result
+func playerUrl(state model.SearchState, videoId string, timestampSeconds string, position int) string {
+	return "/player?" + resultParams(state, videoId, timestampSeconds, position).Encode()
+}
+
+func resultParams(state model.SearchState, videoId string, timestampSeconds string, position int) url.Values {
+	params := state.WithPage(0).Values()
+	params.Set("v", videoId)
+	params.Set("t", timestampSeconds)
+	if position != 0 {
+		params.Set("pos", strconv.Itoa(position))
+	}
+	return params
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bevane/safina-society-search/internal/link"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// fakeMeilisearch returns a client of a server answering requests with the
+// handler
+func fakeMeilisearch(t *testing.T, handler http.HandlerFunc) meilisearch.ServiceManager {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return meilisearch.New(server.URL)
+}
+
+// decodeBody decodes the JSON body of the request into v
+func decodeBody(t *testing.T, r *http.Request, v any) {
+	t.Helper()
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		t.Errorf("unable to decode %s body: %v", r.URL.Path, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestGetSearchResultsFederated(t *testing.T) {
+	collections := []model.Collection{
+		{Id: "safina", IndexName: "safina", ChannelName: "Safina Society"},
+		{Id: "other", IndexName: "other", ChannelName: "Other Channel"},
+	}
+	client := fakeMeilisearch(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/multi-search" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		var body meilisearch.MultiSearchRequest
+		decodeBody(t, r, &body)
+		if body.Federation == nil || body.Federation.Offset != 10 || body.Federation.Limit != hitsPerPage {
+			t.Errorf("federation = %+v, want the second page of %d hits", body.Federation, hitsPerPage)
+		}
+		if len(body.Queries) != len(collections) {
+			t.Fatalf("got %d queries, want one per collection", len(body.Queries))
+		}
+		for i, query := range body.Queries {
+			if query.IndexUID != collections[i].IndexName || query.Query != "patience" {
+				t.Errorf("query %d searches %q in %q", i, query.Query, query.IndexUID)
+			}
+			// federated searches reject paging each query
+			if query.Page != 0 || query.HitsPerPage != 0 || query.Offset != 0 || query.Limit != 0 {
+				t.Errorf("query %d is paged: %+v", i, query)
+			}
+		}
+		var hits []map[string]any
+		for i := range hitsPerPage {
+			hits = append(hits, map[string]any{
+				"id":          fmt.Sprintf("video%06d", i),
+				"title":       "Patience",
+				"transcript":  "1\n00:00:01,000 --> 00:00:02,000\nhave patience\n",
+				"_federation": map[string]any{"indexUid": collections[i%2].IndexName},
+			})
+		}
+		writeJSON(w, map[string]any{"hits": hits, "offset": 10, "limit": hitsPerPage, "estimatedTotalHits": 73})
+	})
+
+	state := model.SearchState{Query: "patience", Page: 2}
+	results, totalPages, err := getSearchResults(context.Background(), collections, state, nil, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.TotalHits != 73 || totalPages != maxPages {
+		t.Errorf("total hits %d and pages %d, want 73 and %d", results.TotalHits, totalPages, maxPages)
+	}
+	if len(results.Items) != hitsPerPage {
+		t.Fatalf("got %d results, want a page of %d", len(results.Items), hitsPerPage)
+	}
+	for i, item := range results.Items {
+		if want := collections[i%2].ChannelName; item.ChannelName != want {
+			t.Errorf("result %d is labeled %q, want %q", i, item.ChannelName, want)
+		}
+	}
+}
+
+func TestGetSearchResultsRetrieves(t *testing.T) {
+	tests := []struct {
+		lang            string
+		vector          []float32
+		retrieve        []string
+		retrieveVectors bool
+	}{
+		{"", nil, []string{"id", "title", "uploadDate", "transcript", "vectorStarts"}, false},
+		{"", []float32{1, 0}, []string{"id", "title", "uploadDate", "transcript", "vectorStarts"}, true},
+		{"ar", []float32{1, 0}, []string{"id", "title", "uploadDate", "transcripts.ar", "vectorStarts"}, false},
+	}
+	for _, test := range tests {
+		client := fakeMeilisearch(t, func(w http.ResponseWriter, r *http.Request) {
+			var body meilisearch.SearchRequest
+			decodeBody(t, r, &body)
+			if !slices.Equal(body.AttributesToRetrieve, test.retrieve) || body.RetrieveVectors != test.retrieveVectors {
+				t.Errorf("lang %q retrieves %q and vectors %v, want %q and %v", test.lang, body.AttributesToRetrieve, body.RetrieveVectors, test.retrieve, test.retrieveVectors)
+			}
+			writeJSON(w, map[string]any{"hits": []any{}})
+		})
+		collections := []model.Collection{{Id: "safina", IndexName: "videos"}}
+		state := model.SearchState{Query: "patience", Lang: test.lang, Page: 1}
+		_, _, err := getSearchResults(context.Background(), collections, state, test.vector, client)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetSearchResultsTimestamps(t *testing.T) {
+	timed := "1\n00:00:00,000 --> 00:00:05,000\nin the name of Allah\n\n" +
+		"2\n00:00:05,000 --> 00:01:05,000\nthe most merciful\n\n" +
+		"3\n00:01:05,000 --> 00:01:10,000\nhave patience in hardship\n"
+	untimed := "in the name of Allah have patience in hardship"
+	// position of the first "patience" in the text
+	match := func(text string) []map[string]int {
+		return []map[string]int{{"start": strings.Index(text, "patience"), "length": len("patience")}}
+	}
+	tests := []struct {
+		name       string
+		transcript string
+		matches    map[string]any
+		want       string
+	}{
+		{"match in a later cue", timed, map[string]any{"transcript": match(timed)}, "65"},
+		{"transcript without timings", untimed, map[string]any{"transcript": match(untimed)}, "0"},
+		{"title match only", timed, map[string]any{"title": []map[string]int{{"start": 0, "length": 8}}}, "0"},
+		{"match out of range", timed, map[string]any{"transcript": []map[string]int{{"start": len(timed) + 10, "length": 8}}}, "0"},
+		{"match on a timing line", timed, map[string]any{"transcript": []map[string]int{{"start": 2, "length": 2}}}, "0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fakeMeilisearch(t, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]any{"hits": []map[string]any{{
+					"id":               "AbCdEfGhIj0",
+					"title":            "Patience",
+					"transcript":       test.transcript,
+					"_matchesPosition": test.matches,
+				}}})
+			})
+			collections := []model.Collection{{Id: "safina", IndexName: "videos"}}
+			state := model.SearchState{Query: "patience", Page: 1}
+			results, _, err := getSearchResults(context.Background(), collections, state, nil, client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results.Items) != 1 {
+				t.Fatalf("got %d results, want 1", len(results.Items))
+			}
+			item := results.Items[0]
+			start, _ := strconv.Atoi(test.want)
+			wantUrl := link.Video{Id: "AbCdEfGhIj0", Start: start}.Watch()
+			if item.TimestampSeconds != test.want || item.Url != wantUrl {
+				t.Errorf("timestamp %q and url %s, want %q and %s", item.TimestampSeconds, item.Url, test.want, wantUrl)
+			}
+			// a result without passages shows the start of the transcript
+			if len(item.Snippets) == 0 && test.transcript == timed && !strings.Contains(item.Snippet.String(), "in the name of Allah") {
+				t.Errorf("snippet = %q, want the start of the transcript", item.Snippet.String())
+			}
+		})
+	}
+}
+
+func TestHandlerSearchPageOutOfRange(t *testing.T) {
+	cfg := &Config{collections: []model.Collection{{Id: "safina", IndexName: "videos"}}}
+	for _, page := range []int{0, maxPages + 1} {
+		r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/search?q=patience&page=%d", page), nil)
+		r.Header.Set("Hx-Request", "true")
+		w := httptest.NewRecorder()
+		cfg.handlerSearch(w, r)
+		want := fmt.Sprintf("between 1 and %d", maxPages)
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("page %d: %q does not say %q", page, w.Body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+var ErrNoDelivery = errors.New("no delivery is configured for this subscription")
+
+// Digest lists the new videos that match a subscription
+type Digest struct {
+	Subscription Subscription `json:"subscription"`
+	// search page of the subscription with all the matching videos
+	SearchUrl      string  `json:"searchUrl"`
+	UnsubscribeUrl string  `json:"unsubscribeUrl"`
+	Videos         []Video `json:"videos"`
+}
+
+// Video is a new video and the moment of it the query best matched
+type Video struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+	// link to the moment
+	Url string `json:"url"`
+	// hh:mm:ss, "" if only the title matched
+	Timestamp string `json:"timestamp,omitempty"`
+	Snippet   string `json:"snippet,omitempty"`
+}
+
+// Notifier delivers digests
+type Notifier interface {
+	Notify(ctx context.Context, digest Digest) error
+}
+
+// Notifiers delivers a digest to the webhook of the subscription if it has
+// one and to its email address otherwise. Either notifier can be nil if
+// that kind of delivery is not configured
+type Notifiers struct {
+	Email   Notifier
+	Webhook Notifier
+}
+
+func (n Notifiers) Notify(ctx context.Context, digest Digest) error {
+	switch {
+	case digest.Subscription.WebhookUrl != "" && n.Webhook != nil:
+		return n.Webhook.Notify(ctx, digest)
+	case digest.Subscription.Email != "" && n.Email != nil:
+		return n.Email.Notify(ctx, digest)
+	}
+	return ErrNoDelivery
+}
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// sent as the List-Unsubscribe header if set
+	UnsubscribeUrl string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// SMTPMailer sends emails through an SMTP server, e.g. a local stand-in
+// such as mailpit during development
+type SMTPMailer struct {
+	// host:port
+	Addr string
+	From string
+	// nil if the server does not require authentication
+	Auth smtp.Auth
+}
+
+func NewSMTPMailer(host string, port int, username string, password string, from string) SMTPMailer {
+	mailer := SMTPMailer{Addr: fmt.Sprintf("%s:%d", host, port), From: from}
+	if username != "" {
+		mailer.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return mailer
+}
+
+func (m SMTPMailer) Send(ctx context.Context, message Message) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", message.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if message.UnsubscribeUrl != "" {
+		fmt.Fprintf(&msg, "List-Unsubscribe: <%s>\r\n", message.UnsubscribeUrl)
+	}
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	// net/smtp does not take a context, the send is abandoned if the context
+	// is done before it
+	errs := make(chan error, 1)
+	go func() {
+		errs <- smtp.SendMail(m.Addr, m.Auth, m.From, []string{message.To}, msg.Bytes())
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// EmailNotifier emails digests as plain text
+type EmailNotifier struct {
+	Mailer Mailer
+}
+
+func (n EmailNotifier) Notify(ctx context.Context, digest Digest) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "New videos match your search %s:\n", Describe(digest.Subscription))
+	for _, video := range digest.Videos {
+		fmt.Fprintf(&body, "\n%s\n", video.Title)
+		if video.Snippet != "" {
+			fmt.Fprintf(&body, "[%s] %s\n", video.Timestamp, video.Snippet)
+		}
+		fmt.Fprintf(&body, "%s\n", video.Url)
+	}
+	fmt.Fprintf(&body, "\nSee all results: %s\n", digest.SearchUrl)
+	fmt.Fprintf(&body, "Unsubscribe: %s\n", digest.UnsubscribeUrl)
+	return n.Mailer.Send(ctx, Message{
+		To:             digest.Subscription.Email,
+		Subject:        fmt.Sprintf("%d new videos match %s", len(digest.Videos), Describe(digest.Subscription)),
+		Body:           body.String(),
+		UnsubscribeUrl: digest.UnsubscribeUrl,
+	})
+}
+
+// WebhookNotifier posts digests as JSON to the webhook of the subscription
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+func (n WebhookNotifier) Notify(ctx context.Context, digest Digest) error {
+	payload, err := json.Marshal(digest)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, digest.Subscription.WebhookUrl, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Describe names the search of the subscription in messages
+func Describe(subscription Subscription) string {
+	switch {
+	case subscription.Query != "" && subscription.Topic != "":
+		return fmt.Sprintf("“%s” about %s", subscription.Query, subscription.Topic)
+	case subscription.Query != "":
+		return fmt.Sprintf("“%s”", subscription.Query)
+	}
+	return "about " + subscription.Topic
+}
@@ -0,0 +1,229 @@
+package alerts
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpServer accepts one message on a local port as an SMTP server would
+// and sends its data on the channel
+func smtpServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) {
+			conn.Write([]byte(line + "\r\n"))
+		}
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case command == "DATA":
+				reply("354 end data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(line, "."))
+				}
+				messages <- data.String()
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, messages := smtpServer(t)
+	mailer := SMTPMailer{Addr: addr, From: "alerts@example.com"}
+	err := mailer.Send(context.Background(), Message{
+		To: "reader@example.com",
+		// a line break cannot add a header
+		Subject:        "New videos match “patience”\r\nBcc: victim@example.com",
+		Body:           "first line\n.\nlast line\n",
+		UnsubscribeUrl: "https://example.com/alerts/unsubscribe?id=abc",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data string
+	select {
+	case data = <-messages:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message was received")
+	}
+
+	message, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to := message.Header.Get("To"); to != "reader@example.com" {
+		t.Errorf("To = %q", to)
+	}
+	if bcc := message.Header.Get("Bcc"); bcc != "" {
+		t.Errorf("the subject added the header Bcc: %s", bcc)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if err != nil || !strings.HasPrefix(subject, "New videos match “patience”") {
+		t.Errorf("Subject = %q, %v", subject, err)
+	}
+	if unsubscribe := message.Header.Get("List-Unsubscribe"); unsubscribe != "<https://example.com/alerts/unsubscribe?id=abc>" {
+		t.Errorf("List-Unsubscribe = %q", unsubscribe)
+	}
+	body, _ := io.ReadAll(message.Body)
+	if string(body) != "first line\r\n.\r\nlast line\r\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestSMTPMailerContext(t *testing.T) {
+	// a server that never greets the client
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	mailer := SMTPMailer{Addr: listener.Addr().String(), From: "alerts@example.com"}
+	err = mailer.Send(ctx, Message{To: "reader@example.com", Subject: "subject", Body: "body"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the deadline to be exceeded", err)
+	}
+}
+
+// recordingMailer keeps the messages it is asked to send
+type recordingMailer struct {
+	messages []Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, message Message) error {
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+var testDigest = Digest{
+	Subscription:   Subscription{Id: "abc", Query: "patience", Email: "reader@example.com", Confirmed: true},
+	SearchUrl:      "https://example.com/search?q=patience",
+	UnsubscribeUrl: "https://example.com/alerts/unsubscribe?id=abc",
+	Videos: []Video{
+		{Id: "AbCdEfGhIj0", Title: "Patience in hardship", Url: "https://www.youtube.com/watch?v=AbCdEfGhIj0&t=65s", Timestamp: "00:01:05", Snippet: "have patience in hardship"},
+		{Id: "KlMnOpQrSt1", Title: "Patience", Url: "https://www.youtube.com/watch?v=KlMnOpQrSt1"},
+	},
+}
+
+func TestEmailNotifier(t *testing.T) {
+	mailer := &recordingMailer{}
+	err := EmailNotifier{Mailer: mailer}.Notify(context.Background(), testDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mailer.messages) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(mailer.messages))
+	}
+	message := mailer.messages[0]
+	if message.To != "reader@example.com" || message.Subject != "2 new videos match “patience”" || message.UnsubscribeUrl != testDigest.UnsubscribeUrl {
+		t.Errorf("message = %+v", message)
+	}
+	for _, want := range []string{
+		"Patience in hardship\n[00:01:05] have patience in hardship\nhttps://www.youtube.com/watch?v=AbCdEfGhIj0&t=65s\n",
+		"Patience\nhttps://www.youtube.com/watch?v=KlMnOpQrSt1\n",
+		"See all results: https://example.com/search?q=patience\n",
+		"Unsubscribe: https://example.com/alerts/unsubscribe?id=abc\n",
+	} {
+		if !strings.Contains(message.Body, want) {
+			t.Errorf("body does not contain %q:\n%s", want, message.Body)
+		}
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Digest
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		err := json.NewDecoder(r.Body).Decode(&received)
+		if err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	digest := testDigest
+	digest.Subscription.Email = ""
+	digest.Subscription.WebhookUrl = server.URL
+	notifier := WebhookNotifier{Client: server.Client()}
+	err := notifier.Notify(context.Background(), digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.Subscription.Id != "abc" || len(received.Videos) != 2 || received.Videos[0].Timestamp != "00:01:05" {
+		t.Errorf("received %+v", received)
+	}
+
+	status = http.StatusInternalServerError
+	if err := notifier.Notify(context.Background(), digest); err == nil {
+		t.Error("a failed delivery is not an error")
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	email := &recordingMailer{}
+	webhook := &recordingMailer{}
+	notifiers := Notifiers{Email: EmailNotifier{Mailer: email}, Webhook: EmailNotifier{Mailer: webhook}}
+
+	digest := testDigest
+	digest.Subscription.WebhookUrl = "https://example.com/hook"
+	if err := notifiers.Notify(context.Background(), digest); err != nil || len(webhook.messages) != 1 || len(email.messages) != 0 {
+		t.Errorf("the webhook is not preferred: %v", err)
+	}
+	if err := notifiers.Notify(context.Background(), testDigest); err != nil || len(email.messages) != 1 {
+		t.Errorf("the digest is not emailed: %v", err)
+	}
+	err := Notifiers{}.Notify(context.Background(), testDigest)
+	if !errors.Is(err, ErrNoDelivery) {
+		t.Errorf("err = %v, want ErrNoDelivery", err)
+	}
+}
@@ -0,0 +1,227 @@
+// Package alerts saves searches that users subscribed to and delivers
+// digests of the newly ingested videos that match them by email or
+// webhook.
+package alerts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bevane/safina-society-search/internal/jsonfile"
+)
+
+const (
+	// MaxPerEmail is the most confirmed subscriptions an email address can
+	// have, so that digests cannot flood an inbox
+	MaxPerEmail = 20
+	// MaxPendingPerEmail is the most subscriptions an email address can
+	// have waiting to be confirmed, so that the form cannot be used to
+	// flood an inbox with confirmation emails. Unconfirmed subscriptions
+	// do not count towards MaxPerEmail so that they cannot lock the owner
+	// of the address out
+	MaxPendingPerEmail = 3
+	// MaxQueryLength is the longest query in characters a subscription can
+	// have, the query is written in the subject of emails
+	MaxQueryLength = 200
+	// ConfirmWithin is how long a subscription can wait to be confirmed,
+	// unconfirmed subscriptions are removed after it
+	ConfirmWithin = 48 * time.Hour
+)
+
+var (
+	ErrNotFound       = errors.New("subscription not found")
+	ErrTooMany        = errors.New("too many subscriptions for this email address")
+	ErrTooManyPending = errors.New("too many unconfirmed subscriptions for this email address")
+	ErrQueryTooLong   = errors.New("query is too long")
+)
+
+// Subscription is a saved search whose new matches are delivered either to
+// an email address or to a webhook
+type Subscription struct {
+	// random token that identifies the subscription in the confirm and
+	// unsubscribe links
+	Id string `json:"id"`
+	// id of the collection that is searched
+	Collection string `json:"collection"`
+	Query      string `json:"query"`
+	Lang       string `json:"lang,omitempty"`
+	Topic      string `json:"topic,omitempty"`
+	Email      string `json:"email,omitempty"`
+	WebhookUrl string `json:"webhookUrl,omitempty"`
+	// digests are only delivered to email addresses that confirmed the
+	// subscription
+	Confirmed bool      `json:"confirmed"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Store keeps the subscriptions in a JSON file
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewStore(path string) (*Store, error) {
+	if path == "" {
+		return nil, errors.New("alerts file path is empty")
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{path: path}, nil
+}
+
+// Expired reports whether the subscription was not confirmed in time
+func (s Subscription) Expired(now time.Time) bool {
+	return !s.Confirmed && now.Sub(s.CreatedAt) > ConfirmWithin
+}
+
+// List returns every subscription that has not expired in the order they
+// were added
+func (s *Store) List() ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var subscriptions []Subscription
+	err := jsonfile.Read(s.path, &subscriptions)
+	return withoutExpired(subscriptions, time.Now()), err
+}
+
+// Get returns the subscription with the id if it has not expired
+func (s *Store) Get(id string) (Subscription, error) {
+	subscriptions, err := s.List()
+	if err != nil {
+		return Subscription{}, err
+	}
+	i := slices.IndexFunc(subscriptions, func(subscription Subscription) bool {
+		return subscription.Id == id
+	})
+	if i < 0 {
+		return Subscription{}, ErrNotFound
+	}
+	return subscriptions[i], nil
+}
+
+// Add saves the subscription with a new id and returns it
+func (s *Store) Add(subscription Subscription) (Subscription, error) {
+	if utf8.RuneCountInString(subscription.Query) > MaxQueryLength {
+		return Subscription{}, ErrQueryTooLong
+	}
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	subscription.Id = hex.EncodeToString(id)
+	subscription.CreatedAt = time.Now().UTC()
+	subscription.Email = normalizeEmail(subscription.Email)
+	err = s.update(func(subscriptions []Subscription) ([]Subscription, error) {
+		if subscription.Email != "" {
+			confirmed, pending := countByEmail(subscriptions, subscription.Email)
+			if confirmed >= MaxPerEmail {
+				return nil, ErrTooMany
+			}
+			if !subscription.Confirmed && pending >= MaxPendingPerEmail {
+				return nil, ErrTooManyPending
+			}
+		}
+		return append(subscriptions, subscription), nil
+	})
+	return subscription, err
+}
+
+// Confirm marks the subscription as confirmed and returns it. Expired
+// subscriptions are not found
+func (s *Store) Confirm(id string) (Subscription, error) {
+	var confirmed Subscription
+	err := s.update(func(subscriptions []Subscription) ([]Subscription, error) {
+		i := slices.IndexFunc(subscriptions, func(subscription Subscription) bool {
+			return subscription.Id == id
+		})
+		if i < 0 {
+			return nil, ErrNotFound
+		}
+		if subscriptions[i].Confirmed {
+			confirmed = subscriptions[i]
+			return subscriptions, nil
+		}
+		if count, _ := countByEmail(subscriptions, subscriptions[i].Email); count >= MaxPerEmail {
+			return nil, ErrTooMany
+		}
+		subscriptions[i].Confirmed = true
+		confirmed = subscriptions[i]
+		return subscriptions, nil
+	})
+	return confirmed, err
+}
+
+// Remove deletes the subscription
+func (s *Store) Remove(id string) error {
+	return s.update(func(subscriptions []Subscription) ([]Subscription, error) {
+		i := slices.IndexFunc(subscriptions, func(subscription Subscription) bool {
+			return subscription.Id == id
+		})
+		if i < 0 {
+			return nil, ErrNotFound
+		}
+		return slices.Delete(subscriptions, i, i+1), nil
+	})
+}
+
+// update replaces the subscriptions with those returned by fn, expired
+// subscriptions are removed before fn is called
+func (s *Store) update(fn func([]Subscription) ([]Subscription, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var subscriptions []Subscription
+	err := jsonfile.Read(s.path, &subscriptions)
+	if err != nil {
+		return err
+	}
+	subscriptions, err = fn(withoutExpired(subscriptions, time.Now()))
+	if err != nil {
+		return err
+	}
+	return jsonfile.Write(s.path, subscriptions)
+}
+
+func withoutExpired(subscriptions []Subscription, now time.Time) []Subscription {
+	return slices.DeleteFunc(subscriptions, func(subscription Subscription) bool {
+		return subscription.Expired(now)
+	})
+}
+
+// normalizeEmail lowercases the domain of the email address, which is not
+// case sensitive
+func normalizeEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	return email[:at] + strings.ToLower(email[at:])
+}
+
+// countByEmail returns the number of confirmed and unconfirmed
+// subscriptions of the email address. Addresses are compared ignoring case
+// as most mail servers deliver addresses that differ only in case to the
+// same mailbox
+func countByEmail(subscriptions []Subscription, email string) (int, int) {
+	confirmed, pending := 0, 0
+	for _, subscription := range subscriptions {
+		switch {
+		case email == "" || !strings.EqualFold(subscription.Email, email):
+		case subscription.Confirmed:
+			confirmed++
+		default:
+			pending++
+		}
+	}
+	return confirmed, pending
+}
@@ -0,0 +1,134 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/jsonfile"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "alerts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestStoreCaps(t *testing.T) {
+	store := newTestStore(t)
+	email := "reader@example.com"
+	var pending []Subscription
+	for i := range MaxPendingPerEmail {
+		subscription, err := store.Add(Subscription{Query: fmt.Sprintf("patience %d", i), Email: email})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pending = append(pending, subscription)
+	}
+	_, err := store.Add(Subscription{Query: "one more", Email: email})
+	if !errors.Is(err, ErrTooManyPending) {
+		t.Errorf("err = %v, want ErrTooManyPending", err)
+	}
+	// another address is not affected
+	_, err = store.Add(Subscription{Query: "patience", Email: "other@example.com"})
+	if err != nil {
+		t.Errorf("err = %v for another address", err)
+	}
+
+	// unconfirmed subscriptions do not count towards the cap of the owner
+	// of the address
+	for i := range MaxPerEmail {
+		_, err := store.Add(Subscription{Query: fmt.Sprintf("confirmed %d", i), Email: email, Confirmed: true})
+		if err != nil {
+			t.Fatalf("confirmed subscription %d: %v", i, err)
+		}
+	}
+	_, err = store.Add(Subscription{Query: "one more", Email: email, Confirmed: true})
+	if !errors.Is(err, ErrTooMany) {
+		t.Errorf("err = %v, want ErrTooMany", err)
+	}
+	// nor can they be confirmed past the cap
+	_, err = store.Confirm(pending[0].Id)
+	if !errors.Is(err, ErrTooMany) {
+		t.Errorf("confirm err = %v, want ErrTooMany", err)
+	}
+}
+
+func TestStoreEmailCase(t *testing.T) {
+	store := newTestStore(t)
+	emails := []string{"Reader@Example.com", "reader@example.com", "READER@EXAMPLE.COM"}
+	for i := range MaxPendingPerEmail {
+		subscription, err := store.Add(Subscription{Query: fmt.Sprintf("patience %d", i), Email: emails[i%len(emails)]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the domain is lowercased, the local part is kept as it was
+		if !strings.HasSuffix(subscription.Email, "@example.com") {
+			t.Errorf("email %q was not normalized", subscription.Email)
+		}
+	}
+	_, err := store.Add(Subscription{Query: "one more", Email: "rEaDeR@eXaMpLe.CoM"})
+	if !errors.Is(err, ErrTooManyPending) {
+		t.Errorf("err = %v, want ErrTooManyPending for the address in another case", err)
+	}
+}
+
+func TestStoreQueryLength(t *testing.T) {
+	store := newTestStore(t)
+	// the length is in characters, not bytes
+	_, err := store.Add(Subscription{Query: strings.Repeat("ص", MaxQueryLength), Email: "reader@example.com"})
+	if err != nil {
+		t.Errorf("err = %v for a query of %d characters", err, MaxQueryLength)
+	}
+	_, err = store.Add(Subscription{Query: strings.Repeat("a", MaxQueryLength+1), Email: "reader@example.com"})
+	if !errors.Is(err, ErrQueryTooLong) {
+		t.Errorf("err = %v, want ErrQueryTooLong", err)
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	store := newTestStore(t)
+	old := time.Now().Add(-ConfirmWithin - time.Minute)
+	err := jsonfile.Write(store.path, []Subscription{
+		{Id: "expired", Query: "patience", Email: "reader@example.com", CreatedAt: old},
+		{Id: "confirmed", Query: "patience", Email: "reader@example.com", Confirmed: true, CreatedAt: old},
+		{Id: "recent", Query: "patience", Email: "reader@example.com", CreatedAt: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriptions, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subscriptions) != 2 || subscriptions[0].Id != "confirmed" || subscriptions[1].Id != "recent" {
+		t.Errorf("List = %+v, want the confirmed and the recent subscriptions", subscriptions)
+	}
+	_, err = store.Confirm("expired")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("confirm expired err = %v, want ErrNotFound", err)
+	}
+	confirmed, err := store.Confirm("recent")
+	if err != nil || !confirmed.Confirmed {
+		t.Errorf("Confirm(recent) = %+v, %v", confirmed, err)
+	}
+
+	// the expired subscription was removed from the file
+	var saved []Subscription
+	err = jsonfile.Read(store.path, &saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subscription := range saved {
+		if subscription.Id == "expired" {
+			t.Error("the expired subscription is still saved")
+		}
+	}
+}
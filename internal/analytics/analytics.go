@@ -0,0 +1,190 @@
+// Package analytics records anonymized search and click events to an
+// append-only JSON lines file. No IP addresses, user agents or any other
+// data that could identify a user are stored.
+package analytics
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EventSearch = "search"
+	EventClick  = "click"
+)
+
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// normalized search query, see NormalizeQuery
+	Query   string            `json:"query"`
+	Filters map[string]string `json:"filters,omitempty"`
+	// search events only
+	ResultCount int   `json:"resultCount,omitempty"`
+	Page        int   `json:"page,omitempty"`
+	LatencyMs   int64 `json:"latencyMs,omitempty"`
+	// click events only
+	VideoId  string `json:"videoId,omitempty"`
+	Position int    `json:"position,omitempty"`
+}
+
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	retention time.Duration
+}
+
+// NewStore returns a store that appends events to the file at path. Events
+// older than retention are removed by Prune, a retention of 0 keeps
+// events forever
+func NewStore(path string, retention time.Duration) (*Store, error) {
+	if path == "" {
+		return nil, errors.New("analytics file path is empty")
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{path: path, retention: retention}, nil
+}
+
+// NormalizeQuery lowercases the query and collapses whitespace so that
+// queries which only differ in case or spacing are counted together
+func NormalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+func (s *Store) Record(event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.Time = event.Time.UTC()
+	event.Query = NormalizeQuery(event.Query)
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(line)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Events returns all recorded events with a time at or after since
+func (s *Store) Events(since time.Time) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var events []Event
+	err = readEvents(f, func(event Event) {
+		if !event.Time.Before(since) {
+			events = append(events, event)
+		}
+	})
+	return events, err
+}
+
+// Prune rewrites the file without the events that are older than the
+// retention period
+func (s *Store) Prune() error {
+	if s.retention <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-s.retention)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	var writeErr error
+	err = readEvents(f, func(event Event) {
+		if writeErr == nil && !event.Time.Before(cutoff) {
+			writeErr = enc.Encode(event)
+		}
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// PruneEvery prunes the store at the given interval until stop is closed
+func (s *Store) PruneEvery(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := s.Prune()
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func readEvents(r io.Reader, fn func(Event)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event Event
+		// skip lines that cannot be decoded such as a partially written
+		// last line after a crash instead of failing the whole read
+		if json.Unmarshal(scanner.Bytes(), &event) != nil {
+			continue
+		}
+		fn(event)
+	}
+	return scanner.Err()
+}
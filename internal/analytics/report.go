@@ -0,0 +1,163 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+)
+
+type QueryStats struct {
+	Query string
+	// number of times the first page of results was requested, later pages
+	// are not counted as a new search
+	Searches    int
+	ZeroResults int
+	Clicks      int
+	// latency is measured across all pages
+	totalLatencyMs int64
+	pageViews      int
+	MaxLatencyMs   int64
+}
+
+func (q QueryStats) AvgLatencyMs() int64 {
+	if q.pageViews == 0 {
+		return 0
+	}
+	return q.totalLatencyMs / int64(q.pageViews)
+}
+
+func (q QueryStats) ClickThroughRate() float64 {
+	if q.Searches == 0 {
+		return 0
+	}
+	return float64(q.Clicks) / float64(q.Searches)
+}
+
+type DayStats struct {
+	Day         time.Time
+	Searches    int
+	ZeroResults int
+	Clicks      int
+}
+
+type Report struct {
+	Since    time.Time
+	Searches int
+	Clicks   int
+	// sorted by number of searches, most searched first
+	Queries []QueryStats
+	// sorted by day, oldest first
+	Days []DayStats
+}
+
+func (r Report) ClickThroughRate() float64 {
+	if r.Searches == 0 {
+		return 0
+	}
+	return float64(r.Clicks) / float64(r.Searches)
+}
+
+// TopQueries returns up to n of the most searched queries
+func (r Report) TopQueries(n int) []QueryStats {
+	return limit(r.Queries, n)
+}
+
+// ZeroResultQueries returns up to n of the most searched queries that
+// returned no results at least once
+func (r Report) ZeroResultQueries(n int) []QueryStats {
+	var queries []QueryStats
+	for _, q := range r.Queries {
+		if q.ZeroResults > 0 {
+			queries = append(queries, q)
+		}
+	}
+	sort.SliceStable(queries, func(i, j int) bool {
+		return queries[i].ZeroResults > queries[j].ZeroResults
+	})
+	return limit(queries, n)
+}
+
+// SlowestQueries returns up to n queries with the highest average latency
+func (r Report) SlowestQueries(n int) []QueryStats {
+	queries := make([]QueryStats, len(r.Queries))
+	copy(queries, r.Queries)
+	sort.SliceStable(queries, func(i, j int) bool {
+		return queries[i].AvgLatencyMs() > queries[j].AvgLatencyMs()
+	})
+	return limit(queries, n)
+}
+
+// Summarize aggregates events into per query and per day statistics
+func Summarize(events []Event, since time.Time) Report {
+	report := Report{Since: since}
+	queries := map[string]*QueryStats{}
+	days := map[time.Time]*DayStats{}
+	getQuery := func(query string) *QueryStats {
+		q, ok := queries[query]
+		if !ok {
+			q = &QueryStats{Query: query}
+			queries[query] = q
+		}
+		return q
+	}
+	getDay := func(t time.Time) *DayStats {
+		day := t.UTC().Truncate(24 * time.Hour)
+		d, ok := days[day]
+		if !ok {
+			d = &DayStats{Day: day}
+			days[day] = d
+		}
+		return d
+	}
+
+	for _, event := range events {
+		if event.Time.Before(since) {
+			continue
+		}
+		switch event.Type {
+		case EventSearch:
+			q := getQuery(event.Query)
+			q.pageViews++
+			q.totalLatencyMs += event.LatencyMs
+			q.MaxLatencyMs = max(q.MaxLatencyMs, event.LatencyMs)
+			if event.Page > 1 {
+				continue
+			}
+			d := getDay(event.Time)
+			q.Searches++
+			d.Searches++
+			report.Searches++
+			if event.ResultCount == 0 {
+				q.ZeroResults++
+				d.ZeroResults++
+			}
+		case EventClick:
+			getQuery(event.Query).Clicks++
+			getDay(event.Time).Clicks++
+			report.Clicks++
+		}
+	}
+
+	for _, q := range queries {
+		report.Queries = append(report.Queries, *q)
+	}
+	sort.Slice(report.Queries, func(i, j int) bool {
+		if report.Queries[i].Searches != report.Queries[j].Searches {
+			return report.Queries[i].Searches > report.Queries[j].Searches
+		}
+		return report.Queries[i].Query < report.Queries[j].Query
+	})
+	for _, d := range days {
+		report.Days = append(report.Days, *d)
+	}
+	sort.Slice(report.Days, func(i, j int) bool {
+		return report.Days[i].Day.Before(report.Days[j].Day)
+	})
+	return report
+}
+
+func limit(queries []QueryStats, n int) []QueryStats {
+	if len(queries) > n {
+		return queries[:n]
+	}
+	return queries
+}
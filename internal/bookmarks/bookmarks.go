@@ -0,0 +1,301 @@
+// Package bookmarks stores named collections of moments of videos that
+// users save from the results. Collections belong to the anonymous token
+// of the browser that created them and can be shared read-only by their
+// id. They are unrelated to the collections of channels that are searched.
+package bookmarks
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bevane/safina-society-search/internal/jsonfile"
+	"github.com/bevane/safina-society-search/internal/link"
+	"github.com/bevane/safina-society-search/internal/transcript"
+)
+
+// limits that keep a single browser from growing the file without bound
+const (
+	MaxLists      = 50
+	MaxMoments    = 500
+	MaxNameLength = 100
+	MaxNoteLength = 500
+)
+
+// limits of all browsers together. A browser that drops its cookie gets a
+// new owner token and with it new per owner limits, so only these keep the
+// file from growing without bound
+const (
+	MaxTotalLists   = 10000
+	MaxTotalMoments = 100000
+)
+
+var (
+	ErrNotFound    = errors.New("collection not found")
+	ErrInvalidName = errors.New("collection name is empty or too long")
+	ErrNoteTooLong = errors.New("note is too long")
+	ErrTooMany     = errors.New("too many collections or moments")
+	ErrFull        = errors.New("the bookmarks store is full")
+)
+
+// List is a named collection of moments
+type List struct {
+	// random id in the share link
+	Id string `json:"id"`
+	// anonymous token of the browser that created the collection, only it
+	// can change the collection
+	Owner     string    `json:"owner"`
+	Name      string    `json:"name"`
+	Moments   []Moment  `json:"moments"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Moment is a moment of a video with the transcript from it and a note
+type Moment struct {
+	Id      string `json:"id"`
+	VideoId string `json:"videoId"`
+	Title   string `json:"title"`
+	// seconds from the start of the video
+	Start int `json:"start"`
+	// code of the language of the captions, "" for the default language
+	Lang    string    `json:"lang,omitempty"`
+	Snippet string    `json:"snippet"`
+	Note    string    `json:"note,omitempty"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+func (m Moment) Video() link.Video {
+	return link.Video{Id: m.VideoId, Start: m.Start, Captions: m.Lang}
+}
+
+// Timestamp returns the start of the moment as hh:mm:ss
+func (m Moment) Timestamp() string {
+	return transcript.FormatTimestamp(time.Duration(m.Start) * time.Second)
+}
+
+// Url links to the moment on youtube
+func (m Moment) Url() string {
+	return m.Video().Watch()
+}
+
+// clone returns a copy of the collection that does not share its moments
+func (l List) clone() List {
+	l.Moments = slices.Clone(l.Moments)
+	return l
+}
+
+// OwnedBy returns whether owner can change the collection
+func (l List) OwnedBy(owner string) bool {
+	return owner != "" && subtle.ConstantTimeCompare([]byte(l.Owner), []byte(owner)) == 1
+}
+
+// NewToken returns a random token used as an id or an owner token
+func NewToken() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Store keeps the collections in a JSON file. The file is read once and
+// kept in memory, so only the store may change it while the server runs
+type Store struct {
+	mu   sync.Mutex
+	path string
+	// the collections of the file, read on first use
+	lists  []List
+	loaded bool
+}
+
+func NewStore(path string) (*Store, error) {
+	if path == "" {
+		return nil, errors.New("bookmarks file path is empty")
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{path: path}, nil
+}
+
+// Lists returns the collections of the owner, most recently changed first
+func (s *Store) Lists(owner string) ([]List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lists, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var owned []List
+	for _, list := range lists {
+		if list.OwnedBy(owner) {
+			owned = append(owned, list.clone())
+		}
+	}
+	slices.SortStableFunc(owned, func(a, b List) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	return owned, nil
+}
+
+// Get returns the collection with the id
+func (s *Store) Get(id string) (List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lists, err := s.load()
+	if err != nil {
+		return List{}, err
+	}
+	i := slices.IndexFunc(lists, func(list List) bool {
+		return list.Id == id
+	})
+	if i < 0 {
+		return List{}, ErrNotFound
+	}
+	return lists[i].clone(), nil
+}
+
+// Save adds the moment to the collection of the owner with the id, or to a
+// new collection with the name if id is "". It returns the collection
+func (s *Store) Save(owner string, id string, name string, moment Moment) (List, error) {
+	if utf8.RuneCountInString(moment.Note) > MaxNoteLength {
+		return List{}, ErrNoteTooLong
+	}
+	var err error
+	moment.Id, err = NewToken()
+	if err != nil {
+		return List{}, err
+	}
+	now := time.Now().UTC()
+	moment.AddedAt = now
+	var saved List
+	err = s.update(func(lists []List) ([]List, error) {
+		total := 0
+		for _, list := range lists {
+			total += len(list.Moments)
+		}
+		if total >= MaxTotalMoments {
+			return nil, ErrFull
+		}
+		i := slices.IndexFunc(lists, func(list List) bool {
+			return id != "" && list.Id == id && list.OwnedBy(owner)
+		})
+		if id != "" && i < 0 {
+			return nil, ErrNotFound
+		}
+		if i < 0 {
+			name = strings.TrimSpace(name)
+			if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+				return nil, ErrInvalidName
+			}
+			count := 0
+			for _, list := range lists {
+				if list.OwnedBy(owner) {
+					count++
+				}
+			}
+			if count >= MaxLists {
+				return nil, ErrTooMany
+			}
+			if len(lists) >= MaxTotalLists {
+				return nil, ErrFull
+			}
+			listId, err := NewToken()
+			if err != nil {
+				return nil, err
+			}
+			lists = append(lists, List{Id: listId, Owner: owner, Name: name, CreatedAt: now})
+			i = len(lists) - 1
+		}
+		if len(lists[i].Moments) >= MaxMoments {
+			return nil, ErrTooMany
+		}
+		lists[i].Moments = append(lists[i].Moments, moment)
+		lists[i].UpdatedAt = now
+		saved = lists[i]
+		return lists, nil
+	})
+	return saved, err
+}
+
+// RemoveMoment removes the moment from the collection of the owner
+func (s *Store) RemoveMoment(owner string, id string, momentId string) error {
+	return s.update(func(lists []List) ([]List, error) {
+		i := slices.IndexFunc(lists, func(list List) bool {
+			return list.Id == id && list.OwnedBy(owner)
+		})
+		if i < 0 {
+			return nil, ErrNotFound
+		}
+		lists[i].Moments = slices.DeleteFunc(lists[i].Moments, func(moment Moment) bool {
+			return moment.Id == momentId
+		})
+		lists[i].UpdatedAt = time.Now().UTC()
+		return lists, nil
+	})
+}
+
+// Delete deletes the collection of the owner
+func (s *Store) Delete(owner string, id string) error {
+	return s.update(func(lists []List) ([]List, error) {
+		i := slices.IndexFunc(lists, func(list List) bool {
+			return list.Id == id && list.OwnedBy(owner)
+		})
+		if i < 0 {
+			return nil, ErrNotFound
+		}
+		return slices.Delete(lists, i, i+1), nil
+	})
+}
+
+// load returns the collections, reading the file the first time. The
+// caller must hold the lock and must not modify the collections
+func (s *Store) load() ([]List, error) {
+	if !s.loaded {
+		var lists []List
+		err := jsonfile.Read(s.path, &lists)
+		if err != nil {
+			return nil, err
+		}
+		s.lists = lists
+		s.loaded = true
+	}
+	return s.lists, nil
+}
+
+// update replaces the collections with those returned by fn. fn is given a
+// copy so that the collections are left as they were if it or the write
+// fails
+func (s *Store) update(fn func([]List) ([]List, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lists, err := s.load()
+	if err != nil {
+		return err
+	}
+	lists = slices.Clone(lists)
+	for i := range lists {
+		lists[i] = lists[i].clone()
+	}
+	lists, err = fn(lists)
+	if err != nil {
+		return err
+	}
+	err = jsonfile.Write(s.path, lists)
+	if err != nil {
+		return err
+	}
+	s.lists = lists
+	return nil
+}
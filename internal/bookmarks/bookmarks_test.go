@@ -0,0 +1,211 @@
+package bookmarks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bevane/safina-society-search/internal/jsonfile"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "bookmarks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestSaveOwner(t *testing.T) {
+	store := newTestStore(t)
+	list, err := store.Save("owner", "", " Patience ", Moment{VideoId: "abc", Start: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Name != "Patience" || len(list.Moments) != 1 || list.Moments[0].Id == "" {
+		t.Errorf("saved %+v", list)
+	}
+	list, err = store.Save("owner", list.Id, "", Moment{VideoId: "def"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Moments) != 2 {
+		t.Errorf("got %d moments, want 2", len(list.Moments))
+	}
+
+	// only the owner can add to or change the collection
+	_, err = store.Save("someone else", list.Id, "", Moment{VideoId: "ghi"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("save err = %v, want ErrNotFound", err)
+	}
+	err = store.RemoveMoment("someone else", list.Id, list.Moments[0].Id)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("remove err = %v, want ErrNotFound", err)
+	}
+	err = store.Delete("", list.Id)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("delete err = %v, want ErrNotFound", err)
+	}
+	lists, err := store.Lists("someone else")
+	if err != nil || len(lists) != 0 {
+		t.Errorf("lists of someone else = %v, %v", lists, err)
+	}
+	// but anyone can view it by its id
+	shared, err := store.Get(list.Id)
+	if err != nil || len(shared.Moments) != 2 {
+		t.Errorf("get = %+v, %v", shared, err)
+	}
+
+	err = store.RemoveMoment("owner", list.Id, list.Moments[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Delete("owner", list.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Get(list.Id)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("get err = %v after delete, want ErrNotFound", err)
+	}
+}
+
+func TestSaveValidation(t *testing.T) {
+	store := newTestStore(t)
+	for _, name := range []string{"", "   ", strings.Repeat("a", MaxNameLength+1)} {
+		_, err := store.Save("owner", "", name, Moment{VideoId: "abc"})
+		if !errors.Is(err, ErrInvalidName) {
+			t.Errorf("name %q: err = %v, want ErrInvalidName", name, err)
+		}
+	}
+	// the lengths are in characters, not bytes
+	_, err := store.Save("owner", "", strings.Repeat("ص", MaxNameLength), Moment{VideoId: "abc", Note: strings.Repeat("ص", MaxNoteLength)})
+	if err != nil {
+		t.Errorf("err = %v for a name and note at the limits", err)
+	}
+	_, err = store.Save("owner", "", "Patience", Moment{VideoId: "abc", Note: strings.Repeat("a", MaxNoteLength+1)})
+	if !errors.Is(err, ErrNoteTooLong) {
+		t.Errorf("err = %v, want ErrNoteTooLong", err)
+	}
+}
+
+func TestSaveCaps(t *testing.T) {
+	store := newTestStore(t)
+	var lists []List
+	for i := range MaxLists {
+		lists = append(lists, List{Id: fmt.Sprint(i), Owner: "owner"})
+	}
+	lists[0].Moments = make([]Moment, MaxMoments)
+	err := jsonfile.Write(store.path, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Save("owner", "", "one more", Moment{VideoId: "abc"})
+	if !errors.Is(err, ErrTooMany) {
+		t.Errorf("new collection err = %v, want ErrTooMany", err)
+	}
+	_, err = store.Save("owner", "0", "", Moment{VideoId: "abc"})
+	if !errors.Is(err, ErrTooMany) {
+		t.Errorf("full collection err = %v, want ErrTooMany", err)
+	}
+	// another owner is not affected
+	_, err = store.Save("other", "", "Patience", Moment{VideoId: "abc"})
+	if err != nil {
+		t.Errorf("err = %v for another owner", err)
+	}
+}
+
+func TestSaveTotalCaps(t *testing.T) {
+	// every list has its own owner, as if each was made by a browser that
+	// dropped its cookie
+	store := newTestStore(t)
+	var lists []List
+	for i := range MaxTotalLists {
+		lists = append(lists, List{Id: fmt.Sprint(i), Owner: fmt.Sprint("owner", i)})
+	}
+	err := jsonfile.Write(store.path, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Save("new owner", "", "Patience", Moment{VideoId: "abc"})
+	if !errors.Is(err, ErrFull) {
+		t.Errorf("new collection err = %v, want ErrFull", err)
+	}
+	// existing collections can still be added to
+	_, err = store.Save("owner0", "0", "", Moment{VideoId: "abc"})
+	if err != nil {
+		t.Errorf("err = %v for an existing collection", err)
+	}
+
+	lists = nil
+	for i := range MaxTotalMoments / MaxMoments {
+		lists = append(lists, List{Id: fmt.Sprint(i), Owner: fmt.Sprint("owner", i), Moments: make([]Moment, MaxMoments)})
+	}
+	lists = append(lists, List{Id: "empty", Owner: "owner"})
+	// the file is read once, so a new store reads the new file
+	store = newTestStore(t)
+	err = jsonfile.Write(store.path, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Save("owner", "empty", "", Moment{VideoId: "abc"})
+	if !errors.Is(err, ErrFull) {
+		t.Errorf("err = %v, want ErrFull", err)
+	}
+	// removing moments makes room again
+	err = store.Delete("owner0", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Save("owner", "empty", "", Moment{VideoId: "abc"})
+	if err != nil {
+		t.Errorf("err = %v after making room", err)
+	}
+}
+
+func TestStoreReadsOnce(t *testing.T) {
+	store := newTestStore(t)
+	list, err := store.Save("owner", "", "Patience", Moment{VideoId: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(store.path, []byte("not json"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lists, err := store.Lists("owner")
+	if err != nil || len(lists) != 1 || lists[0].Id != list.Id {
+		t.Errorf("lists = %v, %v, want the saved collection from memory", lists, err)
+	}
+	// changing a returned collection does not change the store
+	lists[0].Moments[0].Note = "changed"
+	got, err := store.Get(list.Id)
+	if err != nil || got.Moments[0].Note != "" {
+		t.Errorf("get = %+v, %v after changing a returned collection", got, err)
+	}
+	// a failed change leaves the collections as they were
+	_, err = store.Save("owner", list.Id, "", Moment{VideoId: "def", Note: strings.Repeat("a", MaxNoteLength+1)})
+	if !errors.Is(err, ErrNoteTooLong) {
+		t.Fatalf("err = %v, want ErrNoteTooLong", err)
+	}
+	err = os.Remove(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Mkdir(store.path, 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.RemoveMoment("owner", list.Id, list.Moments[0].Id)
+	if err == nil {
+		t.Fatal("removing a moment did not fail to write")
+	}
+	got, err = store.Get(list.Id)
+	if err != nil || len(got.Moments) != 1 {
+		t.Errorf("get = %+v, %v after a failed write, want the moment kept", got, err)
+	}
+}
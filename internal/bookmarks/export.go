@@ -0,0 +1,81 @@
+package bookmarks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type exportedList struct {
+	Name      string           `json:"name"`
+	Url       string           `json:"url"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+	Moments   []exportedMoment `json:"moments"`
+}
+
+type exportedMoment struct {
+	VideoId   string `json:"videoId"`
+	Title     string `json:"title"`
+	Start     int    `json:"start"`
+	Timestamp string `json:"timestamp"`
+	Url       string `json:"url"`
+	Snippet   string `json:"snippet"`
+	Note      string `json:"note,omitempty"`
+}
+
+// WriteJSON writes the collection without its owner, url is the share link
+// of the collection
+func WriteJSON(w io.Writer, list List, url string) error {
+	exported := exportedList{Name: list.Name, Url: url, UpdatedAt: list.UpdatedAt, Moments: []exportedMoment{}}
+	for _, moment := range list.Moments {
+		exported.Moments = append(exported.Moments, exportedMoment{
+			VideoId:   moment.VideoId,
+			Title:     moment.Title,
+			Start:     moment.Start,
+			Timestamp: moment.Timestamp(),
+			Url:       moment.Url(),
+			Snippet:   moment.Snippet,
+			Note:      moment.Note,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(exported)
+}
+
+// WriteMarkdown writes the collection as a Markdown list of links to the
+// moments with their transcript quoted, url is the share link of the
+// collection
+func WriteMarkdown(w io.Writer, list List, url string) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n%s\n", markdownEscaper.Replace(list.Name), url)
+	for _, moment := range list.Moments {
+		fmt.Fprintf(&sb, "\n- [%s (%s)](%s)\n", markdownEscaper.Replace(moment.Title), moment.Timestamp(), moment.Url())
+		if moment.Snippet != "" {
+			fmt.Fprintf(&sb, "  > %s\n", markdownEscaper.Replace(moment.Snippet))
+		}
+		if moment.Note != "" {
+			fmt.Fprintf(&sb, "\n  %s\n", strings.Join(strings.Fields(markdownEscaper.Replace(moment.Note)), " "))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// markdownEscaper escapes the characters that would otherwise format text
+// from titles, transcripts and notes, or turn it into links or html
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	">", `\>`,
+	"#", `\#`,
+	"\n", " ",
+	"\r", " ",
+)
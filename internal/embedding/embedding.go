@@ -0,0 +1,154 @@
+// Package embedding computes sentence embeddings on the CPU from pretrained
+// word vectors stored in a local file, so that no model server or network
+// access is needed at ingest or query time.
+package embedding
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EmbedderName is the name of the meilisearch userProvided embedder the
+// vectors are stored under
+const EmbedderName = "local"
+
+type Embedder interface {
+	// Embed returns a normalized vector or nil if none of the words in the
+	// text are known
+	Embed(text string) []float32
+	Dimensions() int
+}
+
+// WordVectors embeds text as the weighted average of the vectors of its
+// words. Frequent words are given less weight, approximating smooth inverse
+// frequency weighting from the rank of the word in the file
+type WordVectors struct {
+	vectors    map[string][]float32
+	weights    map[string]float32
+	dimensions int
+}
+
+// weight parameter of smooth inverse frequency weighting
+const sifWeight = 1e-3
+
+// LoadWordVectors reads word vectors in the GloVe or fastText text format
+// (one word per line followed by its vector, fastText files start with a
+// "count dimensions" header). Files are sorted by word frequency, only the
+// first maxWords words are loaded to bound memory use, 0 loads every word
+func LoadWordVectors(path string, maxWords int) (*WordVectors, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	wv := &WordVectors{
+		vectors: map[string][]float32{},
+		weights: map[string]float32{},
+	}
+	// words in the order of the file, which is by frequency
+	var words []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		// fastText header
+		if lineNumber == 1 && len(fields) == 2 {
+			continue
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		if wv.dimensions == 0 {
+			wv.dimensions = len(fields) - 1
+		}
+		if len(fields)-1 != wv.dimensions {
+			return nil, fmt.Errorf("line %d: expected %d dimensions, found %d", lineNumber, wv.dimensions, len(fields)-1)
+		}
+		vector := make([]float32, wv.dimensions)
+		for i, field := range fields[1:] {
+			value, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			vector[i] = float32(value)
+		}
+		word := strings.ToLower(fields[0])
+		if _, ok := wv.vectors[word]; ok {
+			continue
+		}
+		wv.vectors[word] = vector
+		words = append(words, word)
+		if maxWords > 0 && len(wv.vectors) >= maxWords {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(wv.vectors) == 0 {
+		return nil, errors.New("word vectors file contains no vectors")
+	}
+
+	// estimate the probability of each word from its rank with zipf's law
+	harmonic := math.Log(float64(len(words))) + 0.5772
+	for i, word := range words {
+		probability := 1 / (float64(i+1) * harmonic)
+		wv.weights[word] = float32(sifWeight / (sifWeight + probability))
+	}
+	return wv, nil
+}
+
+func (wv *WordVectors) Dimensions() int {
+	return wv.dimensions
+}
+
+func (wv *WordVectors) Embed(text string) []float32 {
+	sum := make([]float32, wv.dimensions)
+	found := false
+	for _, word := range Tokenize(text) {
+		vector, ok := wv.vectors[word]
+		if !ok {
+			continue
+		}
+		found = true
+		weight := wv.weights[word]
+		for i, value := range vector {
+			sum[i] += weight * value
+		}
+	}
+	if !found {
+		return nil
+	}
+	return normalize(sum)
+}
+
+// Tokenize lowercases text and splits it into words
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+	})
+}
+
+func normalize(vector []float32) []float32 {
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm == 0 {
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] = float32(float64(vector[i]) / norm)
+	}
+	return vector
+}
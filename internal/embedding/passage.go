@@ -0,0 +1,79 @@
+package embedding
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/transcript"
+)
+
+// number of words per embedded passage, long enough to carry the topic of
+// the passage and short enough that it is not averaged away
+const passageWords = 200
+
+type Passage struct {
+	Start time.Duration
+	Text  string
+}
+
+// Passages splits the cues into consecutive passages of about
+// passageWords words, passages start at the start of a cue
+func Passages(cues []transcript.Cue) []Passage {
+	var passages []Passage
+	var words []string
+	var start time.Duration
+	for _, cue := range cues {
+		if len(words) == 0 {
+			start = cue.Start
+		}
+		words = append(words, strings.Fields(cue.Text)...)
+		if len(words) >= passageWords {
+			passages = append(passages, Passage{Start: start, Text: strings.Join(words, " ")})
+			words = nil
+		}
+	}
+	if len(words) > 0 {
+		passages = append(passages, Passage{Start: start, Text: strings.Join(words, " ")})
+	}
+	return passages
+}
+
+// EmbedCues returns a vector for each passage of the cues and the start of
+// the passage in seconds. The title is embedded with every passage so that
+// passages are matched in the context of the video. Passages without a
+// known word are left out
+func EmbedCues(embedder Embedder, title string, cues []transcript.Cue) ([][]float32, []int) {
+	var vectors [][]float32
+	var starts []int
+	for _, passage := range Passages(cues) {
+		vector := embedder.Embed(title + " " + passage.Text)
+		if vector == nil {
+			continue
+		}
+		vectors = append(vectors, vector)
+		starts = append(starts, int(passage.Start.Seconds()))
+	}
+	return vectors, starts
+}
+
+// Nearest returns the index of the vector most similar to the query vector
+// or -1 if there are no vectors. Vectors are normalized so the dot product
+// is the cosine similarity
+func Nearest(query []float32, vectors [][]float32) int {
+	nearest := -1
+	var best float32
+	for i, vector := range vectors {
+		if len(vector) != len(query) {
+			continue
+		}
+		var similarity float32
+		for j := range vector {
+			similarity += vector[j] * query[j]
+		}
+		if nearest == -1 || similarity > best {
+			nearest = i
+			best = similarity
+		}
+	}
+	return nearest
+}
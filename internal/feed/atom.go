@@ -0,0 +1,92 @@
+// Package feed writes Atom feeds so that feed readers can subscribe to
+// searches.
+// see https://www.rfc-editor.org/rfc/rfc4287
+package feed
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type Feed struct {
+	Id    string
+	Title string
+	// url of the feed itself
+	SelfUrl string
+	// url of the page the feed is about
+	AlternateUrl string
+	Author       string
+	Updated      time.Time
+	Entries      []Entry
+}
+
+type Entry struct {
+	Id      string
+	Title   string
+	Url     string
+	Updated time.Time
+	// html, escaped when written
+	ContentHTML string
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Id      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Id      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// Write writes the feed as an Atom document
+func Write(w io.Writer, feed Feed) error {
+	doc := atomFeed{
+		Id:      feed.Id,
+		Title:   feed.Title,
+		Updated: feed.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: feed.SelfUrl},
+			{Rel: "alternate", Type: "text/html", Href: feed.AlternateUrl},
+		},
+		Author: atomAuthor{Name: feed.Author},
+	}
+	for _, entry := range feed.Entries {
+		doc.Entries = append(doc.Entries, atomEntry{
+			Id:      entry.Id,
+			Title:   entry.Title,
+			Updated: entry.Updated.UTC().Format(time.RFC3339),
+			Link:    atomLink{Rel: "alternate", Type: "text/html", Href: entry.Url},
+			Content: atomContent{Type: "html", Text: entry.ContentHTML},
+		})
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(doc)
+}
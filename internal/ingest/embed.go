@@ -0,0 +1,60 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bevane/safina-society-search/internal/embedding"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/transcript"
+)
+
+// Embed computes the passage vectors of the documents in the index that
+// have a transcript but no vectors, or of every document with a transcript
+// if all is set, and returns the number of documents embedded
+func Embed(ctx context.Context, index Index, embedder embedding.Embedder, all bool) (int, error) {
+	documents, err := index.Transcripts(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var changes []model.VideoDocument
+	for _, document := range documents {
+		if document.Transcript == "" || (len(document.VectorStarts) > 0 && !all) {
+			continue
+		}
+		// only the fields that change are sent, the title is sent because
+		// it is not left out of documents when empty
+		change := model.VideoDocument{Id: document.Id, Title: document.Title, Transcript: document.Transcript}
+		err := embedDocument(embedder, &change)
+		if err != nil {
+			return 0, fmt.Errorf("unable to embed transcript of %s: %w", document.Id, err)
+		}
+		if change.Vectors == nil {
+			continue
+		}
+		change.Transcript = ""
+		changes = append(changes, change)
+	}
+	return len(changes), index.UpdateDocuments(ctx, changes)
+}
+
+// embedDocument sets the passage vectors of the transcript in the default
+// language, the word vectors are for a single language so transcripts in
+// other languages are not embedded
+func embedDocument(embedder embedding.Embedder, document *model.VideoDocument) error {
+	if document.Transcript == "" {
+		return nil
+	}
+	cues, err := transcript.ParseSRT(strings.NewReader(document.Transcript))
+	if err != nil {
+		return err
+	}
+	vectors, starts := embedding.EmbedCues(embedder, document.Title, cues)
+	if len(vectors) == 0 {
+		return nil
+	}
+	document.Vectors = map[string][][]float32{embedding.EmbedderName: vectors}
+	document.VectorStarts = starts
+	return nil
+}
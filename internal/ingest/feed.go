@@ -0,0 +1,142 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Upload is a video listed in a channel uploads feed
+type Upload struct {
+	Id          string
+	Title       string
+	PublishedAt time.Time
+	// zero if the feed does not include an updated time
+	UpdatedAt time.Time
+}
+
+// ParseUploads reads a channel uploads list saved to disk. Both the JSON
+// returned by the YouTube Data API (playlistItems, search or videos list)
+// and the channel Atom feed (https://www.youtube.com/feeds/videos.xml) are
+// supported, the format is detected from the first character
+func ParseUploads(r io.Reader) ([]Upload, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("uploads feed is empty")
+	}
+	var uploads []Upload
+	switch data[0] {
+	case '{':
+		uploads, err = parseDataAPI(data)
+	case '<':
+		uploads, err = parseAtom(data)
+	default:
+		return nil, errors.New("unknown uploads feed format: expected JSON or Atom XML")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, upload := range uploads {
+		if upload.Id == "" {
+			return nil, fmt.Errorf("uploads feed contains a video without an id: %q", upload.Title)
+		}
+	}
+	return uploads, nil
+}
+
+type dataAPIResponse struct {
+	Items []struct {
+		// a string for videos.list and an object for search.list
+		Id      json.RawMessage `json:"id"`
+		Snippet struct {
+			PublishedAt time.Time `json:"publishedAt"`
+			Title       string    `json:"title"`
+			ResourceId  struct {
+				VideoId string `json:"videoId"`
+			} `json:"resourceId"`
+		} `json:"snippet"`
+		ContentDetails struct {
+			VideoId          string    `json:"videoId"`
+			VideoPublishedAt time.Time `json:"videoPublishedAt"`
+		} `json:"contentDetails"`
+	} `json:"items"`
+}
+
+func parseDataAPI(data []byte) ([]Upload, error) {
+	response := dataAPIResponse{}
+	err := json.Unmarshal(data, &response)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse YouTube Data API response: %w", err)
+	}
+	uploads := make([]Upload, 0, len(response.Items))
+	for _, item := range response.Items {
+		upload := Upload{
+			Title:       item.Snippet.Title,
+			PublishedAt: item.Snippet.PublishedAt,
+		}
+		// playlistItems.list
+		if item.ContentDetails.VideoId != "" {
+			upload.Id = item.ContentDetails.VideoId
+		} else if item.Snippet.ResourceId.VideoId != "" {
+			upload.Id = item.Snippet.ResourceId.VideoId
+		} else {
+			upload.Id = dataAPIItemId(item.Id)
+		}
+		// snippet.publishedAt of a playlist item is when it was added to the
+		// playlist rather than when the video was published
+		if !item.ContentDetails.VideoPublishedAt.IsZero() {
+			upload.PublishedAt = item.ContentDetails.VideoPublishedAt
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, nil
+}
+
+func dataAPIItemId(raw json.RawMessage) string {
+	var id string
+	if json.Unmarshal(raw, &id) == nil {
+		return id
+	}
+	var searchId struct {
+		VideoId string `json:"videoId"`
+	}
+	if json.Unmarshal(raw, &searchId) == nil {
+		return searchId.VideoId
+	}
+	return ""
+}
+
+type atomFeed struct {
+	Entries []struct {
+		VideoId   string    `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+		Title     string    `xml:"title"`
+		Published time.Time `xml:"published"`
+		Updated   time.Time `xml:"updated"`
+	} `xml:"entry"`
+}
+
+func parseAtom(data []byte) ([]Upload, error) {
+	feed := atomFeed{}
+	err := xml.Unmarshal(data, &feed)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse Atom feed: %w", err)
+	}
+	uploads := make([]Upload, 0, len(feed.Entries))
+	for _, entry := range feed.Entries {
+		uploads = append(uploads, Upload{
+			Id:          entry.VideoId,
+			Title:       entry.Title,
+			PublishedAt: entry.Published,
+			UpdatedAt:   entry.Updated,
+		})
+	}
+	return uploads, nil
+}
@@ -0,0 +1,92 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// Index is the document store that ingestion writes to. It is implemented
+// by MeiliIndex and can be replaced by an in memory stand-in
+type Index interface {
+	// Documents returns every document in the index, transcripts may be
+	// left out
+	Documents(ctx context.Context) ([]model.VideoDocument, error)
+	// Transcripts returns every document with its title, transcript in the
+	// default language, the start of its embedded passages, its related
+	// videos and topics
+	Transcripts(ctx context.Context) ([]model.VideoDocument, error)
+	// UpdateDocuments adds new documents and updates the given fields of
+	// existing documents, fields left empty are not changed
+	UpdateDocuments(ctx context.Context, documents []model.VideoDocument) error
+}
+
+type MeiliIndex struct {
+	index meilisearch.IndexManager
+}
+
+func NewMeiliIndex(searchClient meilisearch.ServiceManager, indexName string) *MeiliIndex {
+	return &MeiliIndex{index: searchClient.Index(indexName)}
+}
+
+const documentsPageSize = 1000
+
+func (m *MeiliIndex) Documents(ctx context.Context) ([]model.VideoDocument, error) {
+	// transcripts are large and not needed to compare documents
+	return m.documents(ctx, []string{"id", "title", "uploadDate", "updatedAt"})
+}
+
+func (m *MeiliIndex) Transcripts(ctx context.Context) ([]model.VideoDocument, error) {
+	return m.documents(ctx, []string{"id", "title", "transcript", "vectorStarts", "related", "topics"})
+}
+
+func (m *MeiliIndex) documents(ctx context.Context, fields []string) ([]model.VideoDocument, error) {
+	var documents []model.VideoDocument
+	for offset := int64(0); ; offset += documentsPageSize {
+		result := meilisearch.DocumentsResult{}
+		err := m.index.GetDocumentsWithContext(ctx, &meilisearch.DocumentsQuery{
+			Offset: offset,
+			Limit:  documentsPageSize,
+			Fields: fields,
+		}, &result)
+		if err != nil {
+			return nil, err
+		}
+		// round trip through json to decode the generic maps into documents
+		raw, err := json.Marshal(result.Results)
+		if err != nil {
+			return nil, err
+		}
+		var page []model.VideoDocument
+		err = json.Unmarshal(raw, &page)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, page...)
+		if len(page) < documentsPageSize {
+			return documents, nil
+		}
+	}
+}
+
+func (m *MeiliIndex) UpdateDocuments(ctx context.Context, documents []model.VideoDocument) error {
+	if len(documents) == 0 {
+		return nil
+	}
+	taskInfo, err := m.index.UpdateDocumentsWithContext(ctx, documents, "id")
+	if err != nil {
+		return err
+	}
+	task, err := m.index.WaitForTaskWithContext(ctx, taskInfo.TaskUID, 500*time.Millisecond)
+	if err != nil {
+		return err
+	}
+	if task.Status != meilisearch.TaskStatusSucceeded {
+		return fmt.Errorf("document update task %d did not succeed: %s %s", taskInfo.TaskUID, task.Status, task.Error.Message)
+	}
+	return nil
+}
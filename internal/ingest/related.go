@@ -0,0 +1,85 @@
+package ingest
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/related"
+	"github.com/bevane/safina-society-search/internal/transcript"
+)
+
+// number of related videos stored for each video
+const relatedVideos = 5
+
+// number of topics stored for each video
+const videoTopics = 5
+
+// Relate computes the related videos of every document in the index from
+// their titles and transcripts in the default language and stores those
+// that changed. It returns the number of documents updated
+func Relate(ctx context.Context, index Index) (int, error) {
+	documents, err := index.Transcripts(ctx)
+	if err != nil {
+		return 0, err
+	}
+	relatedIds := related.Compute(relatedDocuments(documents), relatedVideos)
+
+	var changes []model.VideoDocument
+	for _, document := range documents {
+		ids := relatedIds[document.Id]
+		// empty fields are left out of updates, so a video that is no longer
+		// related to any other keeps its previous related videos
+		if len(ids) == 0 || slices.Equal(ids, document.Related) {
+			continue
+		}
+		// the title is sent because it is not left out of documents when
+		// empty
+		changes = append(changes, model.VideoDocument{Id: document.Id, Title: document.Title, Related: ids})
+	}
+	return len(changes), index.UpdateDocuments(ctx, changes)
+}
+
+// ExtractTopics extracts the key terms of every document in the index from
+// their titles and transcripts in the default language and stores those
+// that changed. It returns the number of documents updated
+func ExtractTopics(ctx context.Context, index Index) (int, error) {
+	documents, err := index.Transcripts(ctx)
+	if err != nil {
+		return 0, err
+	}
+	topics := related.Topics(relatedDocuments(documents), videoTopics)
+
+	var changes []model.VideoDocument
+	for _, document := range documents {
+		words := topics[document.Id]
+		if len(words) == 0 || slices.Equal(words, document.Topics) {
+			continue
+		}
+		changes = append(changes, model.VideoDocument{Id: document.Id, Title: document.Title, Topics: words})
+	}
+	return len(changes), index.UpdateDocuments(ctx, changes)
+}
+
+func relatedDocuments(documents []model.VideoDocument) []related.Document {
+	texts := make([]related.Document, len(documents))
+	for i, document := range documents {
+		texts[i] = related.Document{Id: document.Id, Text: document.Title + " " + transcriptText(document.Transcript)}
+	}
+	return texts
+}
+
+// transcriptText returns the text of the cues of a transcript stored in
+// the index without the cue numbers and timings
+func transcriptText(srt string) string {
+	cues, err := transcript.ParseSRT(strings.NewReader(srt))
+	if err != nil {
+		return ""
+	}
+	texts := make([]string, len(cues))
+	for i, cue := range cues {
+		texts[i] = cue.Text
+	}
+	return strings.Join(texts, " ")
+}
@@ -0,0 +1,76 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/embedding"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// MaxTotalHits is the number of hits a search can page through, the search
+// handler allows as many pages as fit in it
+const MaxTotalHits = 50
+
+// Settings returns the index settings the search handlers rely on. If
+// dimensions is not 0, an embedder is configured for the passage vectors
+// computed at ingest
+func Settings(dimensions int) *meilisearch.Settings {
+	// tokenize transcripts in other languages with the tokenizer of their
+	// language instead of relying on detection
+	var localizedAttributes []*meilisearch.LocalizedAttributes
+	for _, language := range model.Languages {
+		localizedAttributes = append(localizedAttributes, &meilisearch.LocalizedAttributes{
+			Locales:           []string{language.Locale},
+			AttributePatterns: []string{model.TranscriptField(language.Code)},
+		})
+	}
+	settings := &meilisearch.Settings{
+		// the default ranking rules with the transcript quality as the
+		// final tie-breaker
+		RankingRules: []string{
+			"words",
+			"typo",
+			"proximity",
+			"attribute",
+			"sort",
+			"exactness",
+			"transcriptQuality:desc",
+		},
+		// id is filtered on to match saved searches against newly ingested
+		// videos
+		FilterableAttributes: []string{"id", "transcriptQuality", "autoGeneratedCaptions", "languages", "topics"},
+		// search feeds list the newest videos first
+		SortableAttributes: []string{"uploadDate"},
+		// the topics page lists the most common topics first
+		Faceting: &meilisearch.Faceting{
+			MaxValuesPerFacet: 500,
+			SortFacetValuesBy: map[string]meilisearch.SortFacetType{"topics": meilisearch.SortFacetTypeCount},
+		},
+		LocalizedAttributes: localizedAttributes,
+		Pagination:          &meilisearch.Pagination{MaxTotalHits: MaxTotalHits},
+	}
+	if dimensions != 0 {
+		settings.Embedders = map[string]meilisearch.Embedder{
+			embedding.EmbedderName: {Source: "userProvided", Dimensions: dimensions},
+		}
+	}
+	return settings
+}
+
+func (m *MeiliIndex) ApplySettings(ctx context.Context, settings *meilisearch.Settings) error {
+	taskInfo, err := m.index.UpdateSettingsWithContext(ctx, settings)
+	if err != nil {
+		return err
+	}
+	task, err := m.index.WaitForTaskWithContext(ctx, taskInfo.TaskUID, 500*time.Millisecond)
+	if err != nil {
+		return err
+	}
+	if task.Status != meilisearch.TaskStatusSucceeded {
+		return fmt.Errorf("settings update task %d did not succeed: %s %s", taskInfo.TaskUID, task.Status, task.Error.Message)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package ingest
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSettings(t *testing.T) {
+	settings := Settings(0)
+	// sorting only orders videos that match equally well
+	sort := slices.Index(settings.RankingRules, "sort")
+	if sort < 0 || slices.Index(settings.RankingRules, "attribute") > sort || slices.Index(settings.RankingRules, "words") > sort {
+		t.Errorf("ranking rules %q do not sort after the relevance rules", settings.RankingRules)
+	}
+	if !slices.Contains(settings.SortableAttributes, "uploadDate") {
+		t.Errorf("sortable attributes %q do not include uploadDate", settings.SortableAttributes)
+	}
+	if !slices.Contains(settings.FilterableAttributes, "id") {
+		t.Errorf("filterable attributes %q do not include id", settings.FilterableAttributes)
+	}
+	if settings.Embedders != nil {
+		t.Errorf("embedders %v are configured without dimensions", settings.Embedders)
+	}
+	if settings := Settings(300); settings.Embedders == nil {
+		t.Error("no embedder is configured for 300 dimensions")
+	}
+}
@@ -0,0 +1,161 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bevane/safina-society-search/internal/embedding"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/transcript"
+)
+
+// UploadDateLayout is the format of model.VideoDocument.UploadDate
+const UploadDateLayout = "20060102"
+
+type SyncReport struct {
+	New       []Upload
+	Changed   []Upload
+	Unchanged int
+	// ids of new videos for which no transcript was found, they are
+	// ingested without a transcript
+	MissingTranscripts []string
+}
+
+type SyncOptions struct {
+	// only read the index and report what would be ingested
+	DryRun bool
+	// if set, transcripts of new videos are fetched and ingested with them
+	Transcripts transcript.Provider
+	// codes of the languages to fetch transcripts in
+	Languages []string
+	// if set, the transcripts of new videos are embedded for semantic
+	// search
+	Embedder embedding.Embedder
+}
+
+// Plan compares the uploads in a feed with the documents in the index. An
+// upload is new if its id is not in the index and changed if its title
+// differs or it was updated after the document was last ingested. Documents
+// ingested before update times were recorded are only compared by title
+func Plan(uploads []Upload, documents []model.VideoDocument) SyncReport {
+	existing := make(map[string]model.VideoDocument, len(documents))
+	for _, document := range documents {
+		existing[document.Id] = document
+	}
+	report := SyncReport{}
+	seen := map[string]bool{}
+	for _, upload := range uploads {
+		// feeds can list the same video more than once
+		if seen[upload.Id] {
+			continue
+		}
+		seen[upload.Id] = true
+		document, ok := existing[upload.Id]
+		switch {
+		case !ok:
+			report.New = append(report.New, upload)
+		case document.Title != upload.Title,
+			document.UpdatedAt != 0 && upload.UpdatedAt.Unix() > document.UpdatedAt:
+			report.Changed = append(report.Changed, upload)
+		default:
+			report.Unchanged++
+		}
+	}
+	return report
+}
+
+// Sync ingests the new and changed uploads into the index. Transcripts are
+// only fetched for new uploads, for changed uploads only the title, upload
+// date and update time are updated as a change listed in the feed is a
+// change of the metadata. Documents of videos that are no longer in the
+// feed are kept, as feeds only list the latest uploads
+func Sync(ctx context.Context, index Index, uploads []Upload, options SyncOptions) (SyncReport, error) {
+	documents, err := index.Documents(ctx)
+	if err != nil {
+		return SyncReport{}, err
+	}
+	report := Plan(uploads, documents)
+	if options.DryRun {
+		return report, nil
+	}
+	changes := make([]model.VideoDocument, 0, len(report.New)+len(report.Changed))
+	for _, upload := range report.New {
+		document := uploadDocument(upload)
+		if options.Transcripts != nil {
+			for _, language := range options.Languages {
+				t, err := options.Transcripts.Fetch(ctx, upload.Id, language)
+				if errors.Is(err, transcript.ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					return report, fmt.Errorf("unable to fetch %s transcript for %s: %w", language, upload.Id, err)
+				}
+				setTranscript(&document, t)
+			}
+			if len(document.Languages) == 0 {
+				report.MissingTranscripts = append(report.MissingTranscripts, upload.Id)
+			}
+		}
+		if options.Embedder != nil {
+			err := embedDocument(options.Embedder, &document)
+			if err != nil {
+				return report, fmt.Errorf("unable to embed transcript of %s: %w", upload.Id, err)
+			}
+		}
+		changes = append(changes, document)
+	}
+	for _, upload := range report.Changed {
+		changes = append(changes, uploadDocument(upload))
+	}
+	err = index.UpdateDocuments(ctx, changes)
+	if err != nil || len(changes) == 0 {
+		return report, err
+	}
+	// new transcripts change which videos are related to each other and
+	// the weight of the terms in every transcript
+	_, err = Relate(ctx, index)
+	if err != nil {
+		return report, fmt.Errorf("unable to update related videos: %w", err)
+	}
+	_, err = ExtractTopics(ctx, index)
+	if err != nil {
+		return report, fmt.Errorf("unable to update topics: %w", err)
+	}
+	return report, nil
+}
+
+// setTranscript cleans the transcript and stores it in the document field
+// for its language. The provider and quality score are only recorded for
+// the transcript in the default language
+func setTranscript(document *model.VideoDocument, t transcript.Transcript) {
+	cleaned := transcript.Clean(t.Cues, t.AutoGenerated)
+	quality := transcript.Quality(t.Cues, cleaned)
+	t.Cues = cleaned
+	document.Languages = append(document.Languages, t.Language)
+	if t.Language != model.DefaultLanguage {
+		if document.Transcripts == nil {
+			document.Transcripts = map[string]string{}
+		}
+		document.Transcripts[t.Language] = t.SRT()
+		return
+	}
+	document.Transcript = t.SRT()
+	document.TranscriptQuality = quality
+	document.TranscriptProvider = t.Provider
+	document.AutoGeneratedCaptions = t.AutoGenerated
+}
+
+func uploadDocument(upload Upload) model.VideoDocument {
+	document := model.VideoDocument{
+		Id:    upload.Id,
+		Title: upload.Title,
+	}
+	if !upload.PublishedAt.IsZero() {
+		document.UploadDate = upload.PublishedAt.UTC().Format(UploadDateLayout)
+	}
+	if !upload.UpdatedAt.IsZero() {
+		document.UpdatedAt = upload.UpdatedAt.Unix()
+	}
+	return document
+}
@@ -0,0 +1,233 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/transcript"
+)
+
+// memoryIndex is an in memory stand-in for a meilisearch index. Updates
+// only change the fields they set, like meilisearch partial updates
+type memoryIndex struct {
+	documents map[string]model.VideoDocument
+	updates   int
+}
+
+func newMemoryIndex(documents ...model.VideoDocument) *memoryIndex {
+	index := &memoryIndex{documents: map[string]model.VideoDocument{}}
+	for _, document := range documents {
+		index.documents[document.Id] = document
+	}
+	return index
+}
+
+func (m *memoryIndex) Documents(ctx context.Context) ([]model.VideoDocument, error) {
+	var documents []model.VideoDocument
+	for _, document := range m.documents {
+		documents = append(documents, document)
+	}
+	slices.SortFunc(documents, func(a, b model.VideoDocument) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	return documents, nil
+}
+
+func (m *memoryIndex) Transcripts(ctx context.Context) ([]model.VideoDocument, error) {
+	return m.Documents(ctx)
+}
+
+func (m *memoryIndex) UpdateDocuments(ctx context.Context, documents []model.VideoDocument) error {
+	m.updates++
+	for _, update := range documents {
+		document := m.documents[update.Id]
+		data, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(data, &document)
+		if err != nil {
+			return err
+		}
+		m.documents[update.Id] = document
+	}
+	return nil
+}
+
+func uploadIds(uploads []Upload) []string {
+	var ids []string
+	for _, upload := range uploads {
+		ids = append(ids, upload.Id)
+	}
+	return ids
+}
+
+func TestPlan(t *testing.T) {
+	updated := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	documents := []model.VideoDocument{
+		{Id: "unchanged01", Title: "Unchanged"},
+		{Id: "renamed0001", Title: "Old title"},
+		{Id: "updated0001", Title: "Updated", UpdatedAt: updated.Add(-time.Hour).Unix()},
+		{Id: "notupdated1", Title: "Not updated", UpdatedAt: updated.Unix()},
+		// removed from the channel or no longer in the feed, feeds only list
+		// the latest uploads so the document is left as it is
+		{Id: "removed0001", Title: "Removed"},
+	}
+	tests := []struct {
+		name          string
+		uploads       []Upload
+		wantNew       []string
+		wantChanged   []string
+		wantUnchanged int
+	}{
+		{
+			name:    "new upload",
+			uploads: []Upload{{Id: "new00000001", Title: "New"}},
+			wantNew: []string{"new00000001"},
+		},
+		{
+			name:        "changed title",
+			uploads:     []Upload{{Id: "renamed0001", Title: "New title"}},
+			wantChanged: []string{"renamed0001"},
+		},
+		{
+			name:        "updated after it was ingested",
+			uploads:     []Upload{{Id: "updated0001", Title: "Updated", UpdatedAt: updated}},
+			wantChanged: []string{"updated0001"},
+		},
+		{
+			name:          "not updated since it was ingested",
+			uploads:       []Upload{{Id: "notupdated1", Title: "Not updated", UpdatedAt: updated}},
+			wantUnchanged: 1,
+		},
+		{
+			name:          "ingested before update times were recorded",
+			uploads:       []Upload{{Id: "unchanged01", Title: "Unchanged", UpdatedAt: updated}},
+			wantUnchanged: 1,
+		},
+		{
+			name:          "removed uploads are not reported",
+			uploads:       []Upload{{Id: "unchanged01", Title: "Unchanged"}},
+			wantUnchanged: 1,
+		},
+		{
+			name:    "listed twice",
+			uploads: []Upload{{Id: "new00000001", Title: "New"}, {Id: "new00000001", Title: "New"}},
+			wantNew: []string{"new00000001"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Plan(tt.uploads, documents)
+			if got := uploadIds(report.New); !slices.Equal(got, tt.wantNew) {
+				t.Errorf("new = %v, want %v", got, tt.wantNew)
+			}
+			if got := uploadIds(report.Changed); !slices.Equal(got, tt.wantChanged) {
+				t.Errorf("changed = %v, want %v", got, tt.wantChanged)
+			}
+			if report.Unchanged != tt.wantUnchanged {
+				t.Errorf("unchanged = %d, want %d", report.Unchanged, tt.wantUnchanged)
+			}
+		})
+	}
+}
+
+func fixtureUploads(t *testing.T) []Upload {
+	t.Helper()
+	f, err := os.Open("../../docs/fixtures/uploads.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	uploads, err := ParseUploads(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uploads
+}
+
+func TestSyncFixtures(t *testing.T) {
+	index := newMemoryIndex(
+		model.VideoDocument{Id: "zEwIsK0Xwi4", Title: "Overcome ANY Hardship Using This PROPHETIC METHOD | Dr Shadee Elmasry Lecture", Transcript: "1\n00:00:00,000 --> 00:00:02,000\nexisting\n"},
+		model.VideoDocument{Id: "removed0001", Title: "Removed"},
+	)
+	report, err := Sync(context.Background(), index, fixtureUploads(t), SyncOptions{
+		Transcripts: transcript.YtDlpProvider{Dir: "../../docs/fixtures/subs"},
+		Languages:   []string{"en", "ar"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := uploadIds(report.New); !slices.Equal(got, []string{"AbCdEfGhIj0"}) {
+		t.Errorf("new = %v, want [AbCdEfGhIj0]", got)
+	}
+	if len(report.Changed) != 0 || report.Unchanged != 1 {
+		t.Errorf("changed = %d unchanged = %d, want 0 and 1", len(report.Changed), report.Unchanged)
+	}
+	if len(report.MissingTranscripts) != 0 {
+		t.Errorf("missing transcripts = %v, want none", report.MissingTranscripts)
+	}
+
+	document := index.documents["AbCdEfGhIj0"]
+	if document.Title != "A New Upload" || document.UploadDate == "" {
+		t.Errorf("new document = %q uploaded %q, want its title and upload date", document.Title, document.UploadDate)
+	}
+	if document.Transcript == "" || document.Transcripts["ar"] == "" {
+		t.Errorf("new document is missing the en or ar transcript")
+	}
+	if !slices.Equal(document.Languages, []string{"en", "ar"}) {
+		t.Errorf("languages = %v, want [en ar]", document.Languages)
+	}
+	if document.TranscriptProvider == "" {
+		t.Errorf("transcript provider is not recorded")
+	}
+	if got := index.documents["zEwIsK0Xwi4"].Transcript; got != "1\n00:00:00,000 --> 00:00:02,000\nexisting\n" {
+		t.Errorf("unchanged transcript = %q, want it left as it was", got)
+	}
+	if _, ok := index.documents["removed0001"]; !ok {
+		t.Errorf("documents missing from the feed must not be deleted")
+	}
+}
+
+func TestSyncChangedKeepsTranscript(t *testing.T) {
+	index := newMemoryIndex(model.VideoDocument{Id: "AbCdEfGhIj0", Title: "Old title", Transcript: "old transcript"})
+	uploads := []Upload{{Id: "AbCdEfGhIj0", Title: "New title", PublishedAt: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)}}
+	report, err := Sync(context.Background(), index, uploads, SyncOptions{
+		Transcripts: transcript.YtDlpProvider{Dir: "../../docs/fixtures/subs"},
+		Languages:   []string{"en"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changed) != 1 {
+		t.Fatalf("changed = %d, want 1", len(report.Changed))
+	}
+	document := index.documents["AbCdEfGhIj0"]
+	if document.Title != "New title" || document.UploadDate != "20240105" {
+		t.Errorf("document = %q uploaded %q, want the new title and date", document.Title, document.UploadDate)
+	}
+	// only the metadata of changed uploads is updated
+	if document.Transcript != "old transcript" {
+		t.Errorf("transcript = %q, want the transcript to be kept", document.Transcript)
+	}
+}
+
+func TestSyncDryRun(t *testing.T) {
+	index := newMemoryIndex()
+	report, err := Sync(context.Background(), index, fixtureUploads(t), SyncOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.New) != 2 {
+		t.Errorf("new = %d, want 2", len(report.New))
+	}
+	if index.updates != 0 || len(index.documents) != 0 {
+		t.Errorf("a dry run updated the index")
+	}
+}
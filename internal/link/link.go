@@ -0,0 +1,106 @@
+// Package link builds links to youtube videos at a timestamp.
+package link
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/bevane/safina-society-search/internal/model"
+)
+
+const (
+	youtubeHost = "https://www.youtube.com"
+	// the privacy enhanced host does not store cookies until a video is
+	// played
+	privacyEnhancedHost = "https://www.youtube-nocookie.com"
+)
+
+// youtube video ids are 11 characters of base64url
+var videoIdRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+func ValidVideoId(videoId string) bool {
+	return videoIdRegex.MatchString(videoId)
+}
+
+// Video is a video to link to, the id must be valid
+type Video struct {
+	Id string
+	// offset in seconds to start playing at
+	Start int
+	// code of the language to turn captions on in, "" leaves captions as
+	// they are
+	Captions string
+	// start playing the embedded player as soon as it loads
+	Autoplay bool
+}
+
+// Watch returns the canonical url of the video on youtube
+// e.g. https://www.youtube.com/watch?v=KwRUYjugvpk&t=90s
+func (v Video) Watch() string {
+	return v.watch(youtubeHost)
+}
+
+func (v Video) watch(host string) string {
+	params := url.Values{}
+	if v.Start > 0 {
+		params.Set("t", fmt.Sprintf("%ds", v.Start))
+	}
+	v.setCaptions(params)
+	// v first so the link reads like the ones youtube shares
+	watch := host + "/watch?v=" + url.QueryEscape(v.Id)
+	if len(params) == 0 {
+		return watch
+	}
+	return watch + "&" + params.Encode()
+}
+
+// Embed returns the url of the embedded player of the video
+// e.g. https://www.youtube.com/embed/KwRUYjugvpk?start=90, on
+// youtube-nocookie.com if privacyEnhanced
+func (v Video) Embed(privacyEnhanced bool) string {
+	host := youtubeHost
+	if privacyEnhanced {
+		host = privacyEnhancedHost
+	}
+	params := url.Values{}
+	if v.Start > 0 {
+		params.Set("start", fmt.Sprint(v.Start))
+	}
+	if v.Autoplay {
+		params.Set("autoplay", "1")
+	}
+	v.setCaptions(params)
+	return withQuery(host+"/embed/"+url.PathEscape(v.Id), params)
+}
+
+// Thumbnail returns the url of the high quality thumbnail of the video
+func Thumbnail(videoId string) string {
+	return fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", url.PathEscape(videoId))
+}
+
+func (v Video) setCaptions(params url.Values) {
+	if v.Captions == "" {
+		return
+	}
+	params.Set("cc_load_policy", "1")
+	params.Set("cc_lang_pref", v.Captions)
+}
+
+func withQuery(base string, params url.Values) string {
+	if len(params) == 0 {
+		return base
+	}
+	return base + "?" + params.Encode()
+}
+
+// For returns the url results link to, the watch page on
+// youtube-nocookie.com if the user prefers privacy enhanced links and on
+// youtube otherwise
+func For(ctx context.Context, v Video) string {
+	if model.PreferencesFromContext(ctx).PrivacyEnhanced {
+		return v.watch(privacyEnhancedHost)
+	}
+	return v.Watch()
+}
@@ -0,0 +1,108 @@
+package link
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bevane/safina-society-search/internal/model"
+)
+
+func TestValidVideoId(t *testing.T) {
+	tests := []struct {
+		videoId string
+		want    bool
+	}{
+		{"KwRUYjugvpk", true},
+		{"a-b_c-d_e-f", true},
+		{"KwRUYjugvp", false},
+		{"KwRUYjugvpkk", false},
+		{"KwRUYjugv/k", false},
+		{"KwRUYjugv?k", false},
+		{"KwRUYjugvp\n", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if got := ValidVideoId(test.videoId); got != test.want {
+			t.Errorf("ValidVideoId(%q) = %t, want %t", test.videoId, got, test.want)
+		}
+	}
+}
+
+func TestVideoUrls(t *testing.T) {
+	tests := []struct {
+		name         string
+		video        Video
+		watch        string
+		embed        string
+		privateEmbed string
+	}{
+		{
+			name:         "start",
+			video:        Video{Id: "KwRUYjugvpk"},
+			watch:        "https://www.youtube.com/watch?v=KwRUYjugvpk",
+			embed:        "https://www.youtube.com/embed/KwRUYjugvpk",
+			privateEmbed: "https://www.youtube-nocookie.com/embed/KwRUYjugvpk",
+		},
+		{
+			name:         "timestamp",
+			video:        Video{Id: "KwRUYjugvpk", Start: 90},
+			watch:        "https://www.youtube.com/watch?v=KwRUYjugvpk&t=90s",
+			embed:        "https://www.youtube.com/embed/KwRUYjugvpk?start=90",
+			privateEmbed: "https://www.youtube-nocookie.com/embed/KwRUYjugvpk?start=90",
+		},
+		{
+			name:         "captions and autoplay",
+			video:        Video{Id: "KwRUYjugvpk", Start: 3600, Captions: "ar", Autoplay: true},
+			watch:        "https://www.youtube.com/watch?v=KwRUYjugvpk&cc_lang_pref=ar&cc_load_policy=1&t=3600s",
+			embed:        "https://www.youtube.com/embed/KwRUYjugvpk?autoplay=1&cc_lang_pref=ar&cc_load_policy=1&start=3600",
+			privateEmbed: "https://www.youtube-nocookie.com/embed/KwRUYjugvpk?autoplay=1&cc_lang_pref=ar&cc_load_policy=1&start=3600",
+		},
+		{
+			name:         "negative start",
+			video:        Video{Id: "a-b_c-d_e-f", Start: -5},
+			watch:        "https://www.youtube.com/watch?v=a-b_c-d_e-f",
+			embed:        "https://www.youtube.com/embed/a-b_c-d_e-f",
+			privateEmbed: "https://www.youtube-nocookie.com/embed/a-b_c-d_e-f",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.video.Watch(); got != test.watch {
+				t.Errorf("Watch = %s, want %s", got, test.watch)
+			}
+			if got := test.video.Embed(false); got != test.embed {
+				t.Errorf("Embed(false) = %s, want %s", got, test.embed)
+			}
+			if got := test.video.Embed(true); got != test.privateEmbed {
+				t.Errorf("Embed(true) = %s, want %s", got, test.privateEmbed)
+			}
+		})
+	}
+}
+
+func TestFor(t *testing.T) {
+	video := Video{Id: "KwRUYjugvpk", Start: 90}
+	tests := []struct {
+		name        string
+		preferences model.Preferences
+		want        string
+	}{
+		{"youtube", model.Preferences{}, "https://www.youtube.com/watch?v=KwRUYjugvpk&t=90s"},
+		{"privacy enhanced", model.Preferences{PrivacyEnhanced: true}, "https://www.youtube-nocookie.com/watch?v=KwRUYjugvpk&t=90s"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := model.WithPreferences(context.Background(), test.preferences)
+			if got := For(ctx, video); got != test.want {
+				t.Errorf("For = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	want := "https://i.ytimg.com/vi/KwRUYjugvpk/hqdefault.jpg"
+	if got := Thumbnail("KwRUYjugvpk"); got != want {
+		t.Errorf("Thumbnail = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package model
+
+// AllCollectionsId is the id of the collection that searches every
+// registered collection
+const AllCollectionsId = "all"
+
+// Collection is a searchable set of videos, usually a single channel,
+// stored in its own index
+type Collection struct {
+	// used in urls: /c/{id}/search
+	Id          string `json:"id"`
+	IndexName   string `json:"indexName"`
+	ChannelName string `json:"channelName"`
+	LogoUrl     string `json:"logoUrl"`
+	Description string `json:"description"`
+	// whether the index stores passage vectors, offered only when an
+	// embedder is configured
+	Semantic bool `json:"semantic"`
+	// url prefix of the collection's pages, "" for the default collection
+	// which is served from the root
+	Path string `json:"-"`
+}
+
+func (c Collection) HomePath() string {
+	if c.Path == "" {
+		return "/"
+	}
+	return c.Path
+}
+
+func (c Collection) SearchPath() string {
+	return c.Path + "/search"
+}
+
+// SearchUrl links to the results of the search in the collection
+func (c Collection) SearchUrl(state SearchState) string {
+	return c.SearchPath() + "?" + state.Encode()
+}
+
+// FeedUrl links to the feed of the newest videos matching the search in
+// the collection, the page and mode of the search do not apply to it
+func (c Collection) FeedUrl(state SearchState) string {
+	state.Page = 0
+	state.Mode = ""
+	return c.Path + "/search.atom?" + state.Encode()
+}
+
+func (c Collection) AlertsPath() string {
+	return c.Path + "/alerts"
+}
+
+func (c Collection) OpenSearchPath() string {
+	return c.Path + "/opensearch.xml"
+}
+
+func (c Collection) SuggestPath() string {
+	return c.Path + "/suggest"
+}
+
+func (c Collection) TopicsPath() string {
+	return c.Path + "/topics"
+}
+
+func (c Collection) IsAll() bool {
+	return c.Id == AllCollectionsId
+}
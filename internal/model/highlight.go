@@ -0,0 +1,79 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// HighlightedText is text with the parts that match a query marked. It is
+// built from the plain text and the positions of the matches and rendered
+// escaped, so text from the index can never be rendered as html
+type HighlightedText []TextPart
+
+type TextPart struct {
+	Text   string
+	Marked bool
+}
+
+// PlainText returns text with nothing marked
+func PlainText(text string) HighlightedText {
+	var h HighlightedText
+	h.Append(text, false)
+	return h
+}
+
+// Highlight marks the matches in text, positions are byte offsets into
+// text. Matches that overlap a previous match, are out of range or do not
+// start and end on a character boundary are ignored
+func Highlight(text string, matches []Position) HighlightedText {
+	matches = slices.Clone(matches)
+	slices.SortFunc(matches, func(a, b Position) int {
+		return a.Start - b.Start
+	})
+	var h HighlightedText
+	offset := 0
+	for _, match := range matches {
+		end := match.Start + match.Length
+		if match.Start < offset || end > len(text) || match.Length <= 0 {
+			continue
+		}
+		if !utf8.RuneStart(text[match.Start]) || (end < len(text) && !utf8.RuneStart(text[end])) {
+			continue
+		}
+		h.Append(text[offset:match.Start], false)
+		h.Append(text[match.Start:end], true)
+		offset = end
+	}
+	h.Append(text[offset:], false)
+	return h
+}
+
+// Append adds text to the end, joining it with the last part if both are
+// marked or both are not
+func (h *HighlightedText) Append(text string, marked bool) {
+	if text == "" {
+		return
+	}
+	if n := len(*h); n > 0 && (*h)[n-1].Marked == marked {
+		(*h)[n-1].Text += text
+		return
+	}
+	*h = append(*h, TextPart{Text: text, Marked: marked})
+}
+
+// AppendText adds the parts of other to the end
+func (h *HighlightedText) AppendText(other HighlightedText) {
+	for _, part := range other {
+		h.Append(part.Text, part.Marked)
+	}
+}
+
+// String returns the text without the marks
+func (h HighlightedText) String() string {
+	var sb strings.Builder
+	for _, part := range h {
+		sb.WriteString(part.Text)
+	}
+	return sb.String()
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// marked returns the text with the marked parts in brackets
+func marked(h HighlightedText) string {
+	var sb strings.Builder
+	for _, part := range h {
+		if part.Marked {
+			sb.WriteString("[" + part.Text + "]")
+		} else {
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// at returns the position of the first occurrence of match in text
+func at(text string, match string) Position {
+	return Position{Start: strings.Index(text, match), Length: len(match)}
+}
+
+func TestHighlight(t *testing.T) {
+	script := `<script>alert("patience")</script> patience`
+	entities := "&amp; patience &lt;b&gt;"
+	arabic := "بسم الله الرحمن الرحيم"
+	tests := []struct {
+		name    string
+		text    string
+		matches []Position
+		want    string
+	}{
+		{"no matches", "have patience", nil, "have patience"},
+		{"unordered matches", "have patience in hardship", []Position{at("have patience in hardship", "hardship"), at("have patience in hardship", "patience")}, "have [patience] in [hardship]"},
+		{"script", script, []Position{at(script, "script"), {Start: len(script) - 8, Length: 8}}, `<[script]>alert("patience")</script> [patience]`},
+		{"entities", entities, []Position{at(entities, "&amp;"), at(entities, "patience")}, "[&amp;] [patience] &lt;b&gt;"},
+		{"multi-byte runes", arabic, []Position{at(arabic, "الرحمن")}, "بسم الله [الرحمن] الرحيم"},
+		{"inside a rune", arabic, []Position{{Start: 1, Length: 4}, {Start: 0, Length: 3}}, arabic},
+		{"overlapping", "have patience", []Position{{Start: 7, Length: 3}, {Start: 5, Length: 8}, {Start: 10, Length: 3}}, "have [patience]"},
+		{"adjacent", "patience", []Position{{Start: 0, Length: 3}, {Start: 3, Length: 5}}, "[patience]"},
+		{"out of range", "have patience", []Position{{Start: 5, Length: 20}, {Start: 40, Length: 2}, {Start: -3, Length: 5}, {Start: 2, Length: 0}}, "have patience"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := Highlight(test.text, test.matches)
+			if got := marked(h); got != test.want {
+				t.Errorf("Highlight = %q, want %q", got, test.want)
+			}
+			// the text is kept as it is, escaping is left to the views
+			if h.String() != test.text {
+				t.Errorf("Highlight changed the text to %q", h.String())
+			}
+			for _, part := range h {
+				if !utf8.ValidString(part.Text) {
+					t.Errorf("part %q is not valid utf-8", part.Text)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package model
+
+// DefaultLanguage is the language of the transcript stored in the
+// transcript field, transcripts in other languages are stored in the
+// transcripts field keyed by language code
+const DefaultLanguage = "en"
+
+type Language struct {
+	// ISO 639-1 code used in urls, subtitle file names and youtube links
+	Code string
+	// name of the language in the language itself
+	Name string
+	// ISO 639-3 code used by meilisearch to select the tokenizer
+	Locale string
+}
+
+var Languages = []Language{
+	{Code: "en", Name: "English", Locale: "eng"},
+	{Code: "ar", Name: "العربية", Locale: "ara"},
+	{Code: "ur", Name: "اردو", Locale: "urd"},
+}
+
+func LanguageByCode(code string) (Language, bool) {
+	for _, language := range Languages {
+		if language.Code == code {
+			return language, true
+		}
+	}
+	return Language{}, false
+}
+
+// TranscriptField returns the name of the index field holding the
+// transcript in the given language
+func TranscriptField(code string) string {
+	if code == "" || code == DefaultLanguage {
+		return "transcript"
+	}
+	return "transcripts." + code
+}
@@ -1,5 +1,12 @@
 package model
 
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
 type SearchResponseVideos struct {
 	Hits               []FormattedVideoHit `json:"hits"`
 	EstimatedTotalHits int64               `json:"estimatedTotalHits,omitempty"`
@@ -18,33 +25,297 @@ type SearchResponseVideos struct {
 
 type FormattedVideoHit struct {
 	VideoHit
-	Formatted       VideoHit        `json:"_formatted"`
 	MatchesPosition MatchesPosition `json:"_matchesPosition"`
+	// only returned by federated searches
+	Federation *HitFederation `json:"_federation"`
+	// only returned when requested, keyed by embedder name
+	Vectors map[string]RetrievedVectors `json:"_vectors"`
+}
+
+// HitFederation identifies the index a hit of a federated search is from
+type HitFederation struct {
+	IndexUid string `json:"indexUid"`
+}
+
+type RetrievedVectors struct {
+	Embeddings [][]float32 `json:"embeddings"`
 }
 
 type VideoHit struct {
 	Id         string `json:"id"`
 	Title      string `json:"title"`
 	Transcript string `json:"transcript"`
+	// transcripts in languages other than DefaultLanguage
+	Transcripts map[string]string `json:"transcripts"`
+	// start in seconds of the transcript passage each vector embeds
+	VectorStarts []int `json:"vectorStarts"`
+	// YYYYMMDD as written by yt-dlp
+	UploadDate string `json:"uploadDate"`
+}
+
+// TranscriptIn returns the transcript in the given language
+func (v VideoHit) TranscriptIn(code string) string {
+	if code == "" || code == DefaultLanguage {
+		return v.Transcript
+	}
+	return v.Transcripts[code]
+}
+
+// VideoDocument is a video as it is stored in the search index
+type VideoDocument struct {
+	Id         string `json:"id"`
+	Title      string `json:"title"`
+	Transcript string `json:"transcript,omitempty"`
+	// transcripts in languages other than DefaultLanguage keyed by
+	// language code
+	Transcripts map[string]string `json:"transcripts,omitempty"`
+	// codes of all languages the video has a transcript in
+	Languages []string `json:"languages,omitempty"`
+	// YYYYMMDD as written by yt-dlp
+	UploadDate string `json:"uploadDate,omitempty"`
+	// length of the video in seconds
+	Duration string `json:"duration,omitempty"`
+	// name of the transcript provider and whether the captions were
+	// generated by speech recognition
+	TranscriptProvider    string `json:"transcriptProvider,omitempty"`
+	AutoGeneratedCaptions bool   `json:"autoGeneratedCaptions,omitempty"`
+	// score between 0 and 1 computed when the transcript was cleaned
+	TranscriptQuality float64 `json:"transcriptQuality,omitempty"`
+	// unix timestamp of the last change to the video listed in the uploads
+	// feed at the time it was ingested
+	UpdatedAt int64 `json:"updatedAt,omitempty"`
+	// vectors of the passages of the transcript in the default language
+	// keyed by embedder name, see package embedding
+	Vectors map[string][][]float32 `json:"_vectors,omitempty"`
+	// start in seconds of the passage each vector embeds
+	VectorStarts []int `json:"vectorStarts,omitempty"`
+	// ids of the videos with the most similar transcripts, most similar
+	// first
+	Related []string `json:"related,omitempty"`
+	// key terms of the title and transcript, filterable to browse videos
+	// by topic
+	Topics []string `json:"topics,omitempty"`
 }
+
 type Result struct {
+	VideoId string
+	Title   HighlightedText
+	Url     string
+	// loads the video in the player on the page
+	PlayerUrl string
+	// loads the form saving the moment to a collection of bookmarks, "" if
+	// bookmarks are not enabled
+	SaveUrl          string
+	TimestampSeconds string
+	ThumbnailUrl     string
+	Snippet          HighlightedText
+	// best passages around the matches, best first, the snippet is shown
+	// if there are none
+	Snippets     []Snippet
+	MatchesCount int
+	// true if the video was found by meaning and the snippet is the
+	// passage closest to the query
+	Related bool
+	// set if the snippet is the excerpt of a time range of the video,
+	// e.g. "00:40:00-00:50:00"
+	Span string
+	// language of the snippet, "" for the default language
+	Language string
+	// set when results from several collections are shown together
+	ChannelName string
+}
+
+// Snippet is a passage of a transcript linking to the time it is said at
+type Snippet struct {
+	Text HighlightedText
+	// hh:mm:ss
+	Timestamp        string
+	TimestampSeconds string
+	Url              string
+	PlayerUrl        string
+}
+
+// Player is a video playing on the search page from a moment, with the
+// moments the query matches in the video to seek to
+type Player struct {
+	VideoId  string
+	Title    string
+	EmbedUrl string
+	// opens the video on youtube at the moment
+	Url     string
+	Moments []Moment
+	// index of the moment playing, -1 if the video is not playing from one
+	// of the moments
+	Current int
+	// urls of the moments before and after the one playing, "" if there
+	// is none
+	PreviousUrl string
+	NextUrl     string
+	// loads the form saving the moment playing, "" if bookmarks are not
+	// enabled
+	SaveUrl string
+}
+
+// Moment is a time in a video a query matches at
+type Moment struct {
+	// hh:mm:ss
+	Timestamp string
+	PlayerUrl string
+}
+
+// Video is a video shown on its own page or in a list of related videos
+type Video struct {
+	Id           string
 	Title        string
 	Url          string
 	ThumbnailUrl string
-	Snippet      string
-	MatchesCount int
+	// e.g. "2 January 2006", "" if unknown
+	PublishedAt string
+	// language of the transcript, "" for the default language
+	Language   string
+	Transcript []TranscriptLine
+}
+
+type TranscriptLine struct {
+	// hh:mm:ss
+	Timestamp string
+	// links to the timestamp in the video
+	Url  string
+	Text string
 }
 
 type Results struct {
-	Items []Result
+	Items     []Result
+	TotalHits int
+	// feed of the newest videos matching the search, "" if the search has
+	// none
+	FeedUrl string
+	// path the form subscribing to email alerts for the search posts to,
+	// "" if the search cannot be subscribed to
+	AlertPath string
 }
 
 type MatchesPosition struct {
 	Title      []Position `json:"title"`
 	Transcript []Position `json:"transcript"`
+	// matches in transcripts in other languages keyed by language code,
+	// meilisearch reports them under the nested field name e.g.
+	// "transcripts.ar"
+	Transcripts map[string][]Position `json:"-"`
+}
+
+func (m *MatchesPosition) UnmarshalJSON(data []byte) error {
+	fields := map[string][]Position{}
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+	m.Title = fields["title"]
+	m.Transcript = fields["transcript"]
+	m.Transcripts = map[string][]Position{}
+	for field, positions := range fields {
+		if code, ok := strings.CutPrefix(field, "transcripts."); ok {
+			m.Transcripts[code] = positions
+		}
+	}
+	return nil
+}
+
+// TranscriptIn returns the matches in the transcript in the given language
+func (m MatchesPosition) TranscriptIn(code string) []Position {
+	if code == "" || code == DefaultLanguage {
+		return m.Transcript
+	}
+	return m.Transcripts[code]
 }
 
 type Position struct {
 	Start  int `json:"start"`
 	Length int `json:"length"`
 }
+
+// SearchModeHybrid combines keyword search with semantic search
+const SearchModeHybrid = "hybrid"
+
+// SearchState is what the user searched for, it is kept in the url of the
+// results
+type SearchState struct {
+	Query string
+	// "" for the default language
+	Lang string
+	// SearchModeHybrid or "" for keyword search
+	Mode string
+	// only videos with this topic are searched, "" for all videos
+	Topic string
+	Page  int
+}
+
+// names of the url params of the search state
+const (
+	ParamQuery = "q"
+	ParamLang  = "lang"
+	ParamMode  = "mode"
+	ParamTopic = "topic"
+	ParamPage  = "page"
+)
+
+// SearchStateFromValues returns the search state in the url params, the
+// values are not validated. Page is 0 if it is missing or not a number
+func SearchStateFromValues(params url.Values) SearchState {
+	page, _ := strconv.Atoi(params.Get(ParamPage))
+	return SearchState{
+		Query: params.Get(ParamQuery),
+		Lang:  params.Get(ParamLang),
+		Mode:  params.Get(ParamMode),
+		Topic: params.Get(ParamTopic),
+		Page:  page,
+	}
+}
+
+// Values returns the state as url params, the query is always set and the
+// other params only if they are not the default
+func (s SearchState) Values() url.Values {
+	params := url.Values{}
+	params.Set(ParamQuery, s.Query)
+	if s.Lang != "" {
+		params.Set(ParamLang, s.Lang)
+	}
+	if s.Mode != "" {
+		params.Set(ParamMode, s.Mode)
+	}
+	if s.Topic != "" {
+		params.Set(ParamTopic, s.Topic)
+	}
+	if s.Page != 0 {
+		params.Set(ParamPage, strconv.Itoa(s.Page))
+	}
+	return params
+}
+
+// Encode returns the state as an encoded url query
+func (s SearchState) Encode() string {
+	return s.Values().Encode()
+}
+
+// WithPage returns the state with the page changed
+func (s SearchState) WithPage(page int) SearchState {
+	s.Page = page
+	return s
+}
+
+// PageMeta is how a page is previewed when a link to it is shared and the
+// canonical url of the page, urls are absolute
+type PageMeta struct {
+	Title       string
+	Description string
+	Url         string
+	ImageUrl    string
+	// feed of the page for feed readers, "" if it has none
+	FeedUrl string
+}
+
+// TopicCount is a topic with the number of videos about it
+type TopicCount struct {
+	Topic string
+	Count int
+}
@@ -0,0 +1,28 @@
+package model
+
+import "context"
+
+// Preferences are the user's choices of how videos are opened and results
+// are shown, they are kept in cookies
+type Preferences struct {
+	// link to the privacy enhanced player instead of youtube
+	PrivacyEnhanced bool
+	// play videos in the player on the page instead of opening youtube
+	Inline bool
+	// load the next page of results when the end of the results is
+	// reached instead of showing the page numbers
+	InfiniteScroll bool
+}
+
+type contextKey int
+
+const preferencesKey contextKey = iota
+
+func WithPreferences(ctx context.Context, preferences Preferences) context.Context {
+	return context.WithValue(ctx, preferencesKey, preferences)
+}
+
+func PreferencesFromContext(ctx context.Context) Preferences {
+	preferences, _ := ctx.Value(preferencesKey).(Preferences)
+	return preferences
+}
@@ -0,0 +1,140 @@
+// Package related finds videos that talk about similar things and the
+// topics they talk about from the tf-idf vectors of their transcripts.
+package related
+
+import (
+	"math"
+	"sort"
+
+	"github.com/bevane/safina-society-search/internal/embedding"
+)
+
+// number of terms with the highest weight kept for each document. The
+// terms that characterize a document are enough to compare it and keeping
+// fewer bounds the time to compare every pair of documents
+const termsPerDocument = 100
+
+type Document struct {
+	Id   string
+	Text string
+}
+
+type term struct {
+	word   string
+	weight float64
+}
+
+// Compute returns the ids of up to n documents most similar to each
+// document, most similar first. Documents are compared by the cosine
+// similarity of their tf-idf vectors, documents with no terms in common
+// are not related
+func Compute(documents []Document, n int) map[string][]string {
+	vectors, _ := tfidf(documents)
+
+	// documents each term is in, used to only compare documents that share
+	// a term
+	postings := map[string][]int{}
+	for i, vector := range vectors {
+		for _, t := range vector {
+			postings[t.word] = append(postings[t.word], i)
+		}
+	}
+
+	related := make(map[string][]string, len(documents))
+	for i, vector := range vectors {
+		similarities := map[int]float64{}
+		for _, t := range vector {
+			for _, j := range postings[t.word] {
+				if j == i {
+					continue
+				}
+				similarities[j] += t.weight * weightOf(vectors[j], t.word)
+			}
+		}
+		candidates := make([]int, 0, len(similarities))
+		for j := range similarities {
+			candidates = append(candidates, j)
+		}
+		sort.Slice(candidates, func(a, b int) bool {
+			if similarities[candidates[a]] != similarities[candidates[b]] {
+				return similarities[candidates[a]] > similarities[candidates[b]]
+			}
+			return documents[candidates[a]].Id < documents[candidates[b]].Id
+		})
+		ids := []string{}
+		for _, j := range candidates[:min(n, len(candidates))] {
+			ids = append(ids, documents[j].Id)
+		}
+		related[documents[i].Id] = ids
+	}
+	return related
+}
+
+// tfidf returns the normalized tf-idf vector of each document, made of
+// its termsPerDocument highest weighted terms sorted by word, and the
+// number of documents each word is in
+func tfidf(documents []Document) ([][]term, map[string]int) {
+	frequencies := make([]map[string]int, len(documents))
+	documentFrequency := map[string]int{}
+	for i, document := range documents {
+		frequencies[i] = map[string]int{}
+		for _, word := range embedding.Tokenize(document.Text) {
+			if frequencies[i][word] == 0 {
+				documentFrequency[word]++
+			}
+			frequencies[i][word]++
+		}
+	}
+
+	vectors := make([][]term, len(documents))
+	for i, frequency := range frequencies {
+		vector := make([]term, 0, len(frequency))
+		for word, count := range frequency {
+			// words in every document do not tell documents apart and
+			// words in a single document are not shared with any other
+			if documentFrequency[word] == len(documents) || documentFrequency[word] == 1 {
+				continue
+			}
+			idf := math.Log(float64(len(documents)) / float64(documentFrequency[word]))
+			vector = append(vector, term{word: word, weight: (1 + math.Log(float64(count))) * idf})
+		}
+		sortByWeight(vector)
+		vector = vector[:min(termsPerDocument, len(vector))]
+
+		var norm float64
+		for _, t := range vector {
+			norm += t.weight * t.weight
+		}
+		norm = math.Sqrt(norm)
+		for j := range vector {
+			vector[j].weight /= norm
+		}
+		sort.Slice(vector, func(a, b int) bool {
+			return vector[a].word < vector[b].word
+		})
+		vectors[i] = vector
+	}
+	return vectors, documentFrequency
+}
+
+// sortByWeight sorts terms by descending weight, terms with the same
+// weight are sorted by word so results are the same on every run
+func sortByWeight(terms []term) {
+	sort.Slice(terms, func(a, b int) bool {
+		if terms[a].weight != terms[b].weight {
+			return terms[a].weight > terms[b].weight
+		}
+		return terms[a].word < terms[b].word
+	})
+}
+
+// weightOf returns the weight of the word in a vector sorted by word
+func weightOf(vector []term, word string) float64 {
+	i := sort.Search(len(vector), func(i int) bool {
+		return vector[i].word >= word
+	})
+	if i < len(vector) && vector[i].word == word {
+		return vector[i].weight
+	}
+	return 0
+}
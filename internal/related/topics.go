@@ -0,0 +1,59 @@
+package related
+
+import "unicode/utf8"
+
+// words too common in speech to describe what a video is about, the
+// weighting leaves out most words that are common across the catalog but
+// not those that are frequent in a few videos
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, word := range []string{
+		"about", "actually", "after", "again", "also", "always", "another", "anything", "around", "because",
+		"been", "before", "being", "between", "both", "can't", "come", "comes", "coming", "could",
+		"didn't", "does", "doesn't", "doing", "don't", "down", "each", "even", "every", "everything",
+		"from", "getting", "going", "gonna", "good", "have", "having", "he's", "here", "himself",
+		"i'm", "into", "it's", "just", "know", "like", "little", "look", "make", "many",
+		"maybe", "mean", "more", "most", "much", "need", "never", "okay", "only", "other",
+		"over", "people", "really", "right", "said", "same", "says", "she's", "should", "something",
+		"still", "such", "take", "talk", "talking", "tell", "than", "that", "that's", "their",
+		"them", "themselves", "then", "there", "there's", "these", "they", "they're", "thing", "things",
+		"think", "this", "those", "through", "time", "very", "wanna", "want", "we're", "well",
+		"were", "what", "what's", "when", "where", "which", "while", "will", "with", "would",
+		"yeah", "you're", "you've", "your", "yourself",
+	} {
+		stopWords[word] = true
+	}
+}
+
+// shortest word considered as a topic
+const minTopicLength = 4
+
+// fraction of the documents a word has to be in to be a topic, words in
+// fewer documents are too specific to browse by
+const minTopicShare = 0.005
+
+// Topics returns up to n key terms of each document, the terms with the
+// highest tf-idf weight that are shared with other documents
+func Topics(documents []Document, n int) map[string][]string {
+	vectors, documentFrequency := tfidf(documents)
+	minDocuments := max(2, int(minTopicShare*float64(len(documents))))
+	topics := make(map[string][]string, len(documents))
+	for i, vector := range vectors {
+		// tfidf sorts the terms by word, order them by weight again
+		terms := make([]term, 0, len(vector))
+		for _, t := range vector {
+			if stopWords[t.word] || utf8.RuneCountInString(t.word) < minTopicLength || documentFrequency[t.word] < minDocuments {
+				continue
+			}
+			terms = append(terms, t)
+		}
+		sortByWeight(terms)
+		words := []string{}
+		for _, t := range terms[:min(n, len(terms))] {
+			words = append(words, t.word)
+		}
+		topics[documents[i].Id] = words
+	}
+	return topics
+}
@@ -0,0 +1,81 @@
+// Package sitemap writes sitemaps of the pages of the site in the sitemaps
+// protocol, with the video extension for pages about a video.
+// see https://www.sitemaps.org/protocol.html
+package sitemap
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// MaxUrls is the most urls a single sitemap can list, larger sitemaps are
+// split into chunks listed by a sitemap index
+const MaxUrls = 50000
+
+type Url struct {
+	Loc string `xml:"loc"`
+	// YYYY-MM-DD, "" if unknown
+	LastMod string `xml:"lastmod,omitempty"`
+	Video   *Video `xml:"video:video,omitempty"`
+}
+
+// Video describes the video a page is about
+// see https://developers.google.com/search/docs/crawling-indexing/sitemaps/video-sitemaps
+type Video struct {
+	ThumbnailLoc string `xml:"video:thumbnail_loc"`
+	Title        string `xml:"video:title"`
+	Description  string `xml:"video:description"`
+	PlayerLoc    string `xml:"video:player_loc"`
+	// YYYY-MM-DD, "" if unknown
+	PublicationDate string `xml:"video:publication_date,omitempty"`
+}
+
+type urlSet struct {
+	XMLName    xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	XmlnsVideo string   `xml:"xmlns:video,attr"`
+	Urls       []Url    `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name   `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []location `xml:"sitemap"`
+}
+
+type location struct {
+	Loc string `xml:"loc"`
+}
+
+// Chunks splits urls into chunks of at most size urls
+func Chunks(urls []Url, size int) [][]Url {
+	var chunks [][]Url
+	for len(urls) > size {
+		chunks = append(chunks, urls[:size])
+		urls = urls[size:]
+	}
+	return append(chunks, urls)
+}
+
+// WriteUrlSet writes a sitemap listing the urls
+func WriteUrlSet(w io.Writer, urls []Url) error {
+	return write(w, urlSet{
+		XmlnsVideo: "http://www.google.com/schemas/sitemap-video/1.1",
+		Urls:       urls,
+	})
+}
+
+// WriteIndex writes a sitemap index listing the sitemaps at the locations
+func WriteIndex(w io.Writer, locs []string) error {
+	index := sitemapIndex{}
+	for _, loc := range locs {
+		index.Sitemaps = append(index.Sitemaps, location{Loc: loc})
+	}
+	return write(w, index)
+}
+
+func write(w io.Writer, v any) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,141 @@
+package transcript
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// shortest and longest phrase, in words, that is merged when it is
+// repeated in automatically generated captions. Single words are merged as
+// the captions stutter, e.g. "ala ala alihi"
+const (
+	minRepeatedPhrase = 1
+	maxRepeatedPhrase = 4
+)
+
+// Clean orders the cues, drops cues that are empty or fall within the time
+// of the previous cue and normalizes whitespace. The artifacts of
+// automatically generated captions are also fixed: text repeated from the
+// previous cue by rolling captions is merged and immediately repeated words
+// and phrases such as "ala ala alihi" are collapsed. Captions written by a
+// person are not merged as their repetitions, e.g. "no no", are spoken.
+// The cues passed in are not modified
+func Clean(cues []Cue, autoGenerated bool) []Cue {
+	sorted := make([]Cue, len(cues))
+	copy(sorted, cues)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	cleaned := make([]Cue, 0, len(sorted))
+	var previousWords []string
+	for _, cue := range sorted {
+		words := strings.Fields(cue.Text)
+		if autoGenerated {
+			// rolling captions repeat the end of the previous cue at the
+			// start of the next one
+			words = words[overlap(previousWords, words):]
+			words = collapseRepeats(words)
+		}
+		if len(words) == 0 || cue.End <= cue.Start {
+			continue
+		}
+		if len(cleaned) > 0 {
+			last := &cleaned[len(cleaned)-1]
+			// a cue within the time of the previous cue is a duplicate
+			if cue.End <= last.End {
+				continue
+			}
+			if cue.Start < last.End {
+				last.End = cue.Start
+			}
+		}
+		cleaned = append(cleaned, Cue{Start: cue.Start, End: cue.End, Text: strings.Join(words, " ")})
+		previousWords = strings.Fields(cue.Text)
+	}
+	return cleaned
+}
+
+// overlap returns the number of words at the start of current that repeat
+// the words at the end of previous
+func overlap(previous []string, current []string) int {
+	for n := min(len(previous), len(current)); n >= minRepeatedPhrase; n-- {
+		if equalWords(previous[len(previous)-n:], current[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// collapseRepeats removes words and phrases of up to maxRepeatedPhrase words
+// that are immediately repeated
+func collapseRepeats(words []string) []string {
+	result := make([]string, 0, len(words))
+	for _, word := range words {
+		result = append(result, word)
+		for n := minRepeatedPhrase; n <= maxRepeatedPhrase && 2*n <= len(result); n++ {
+			end := len(result)
+			if equalWords(result[end-2*n:end-n], result[end-n:]) {
+				result = result[:end-n]
+				break
+			}
+		}
+	}
+	return result
+}
+
+func equalWords(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Quality scores a transcript between 0 and 1 by comparing the cues as
+// they were obtained with the cleaned cues. Transcripts score lower the
+// more text had to be removed as duplicated, the more cues were empty, the
+// less punctuation they contain (automatically generated captions have
+// none) and the more of the time between the first and the last cue has no
+// captions
+func Quality(original []Cue, cleaned []Cue) float64 {
+	if len(original) == 0 || len(cleaned) == 0 {
+		return 0
+	}
+	originalWords := 0
+	nonEmpty := 0
+	for _, cue := range original {
+		words := len(strings.Fields(cue.Text))
+		originalWords += words
+		if words > 0 {
+			nonEmpty++
+		}
+	}
+	cleanedWords := 0
+	punctuated := 0
+	var captioned time.Duration
+	for _, cue := range cleaned {
+		cleanedWords += len(strings.Fields(cue.Text))
+		if strings.ContainsAny(cue.Text, ".,?!") {
+			punctuated++
+		}
+		captioned += cue.End - cue.Start
+	}
+	span := cleaned[len(cleaned)-1].End - cleaned[0].Start
+
+	kept := float64(cleanedWords) / float64(originalWords)
+	notEmpty := float64(nonEmpty) / float64(len(original))
+	punctuation := float64(punctuated) / float64(len(cleaned))
+	coverage := 1.0
+	if span > 0 {
+		coverage = min(1, float64(captioned)/float64(span))
+	}
+	score := 0.3*kept + 0.2*notEmpty + 0.2*punctuation + 0.3*coverage
+	return math.Round(score*100) / 100
+}
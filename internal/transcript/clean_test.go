@@ -0,0 +1,135 @@
+package transcript
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// cues returns one second cues with the given texts
+func cues(texts ...string) []Cue {
+	result := make([]Cue, len(texts))
+	for i, text := range texts {
+		result[i] = Cue{Start: time.Duration(i) * time.Second, End: time.Duration(i+1) * time.Second, Text: text}
+	}
+	return result
+}
+
+func texts(cues []Cue) []string {
+	result := make([]string, len(cues))
+	for i, cue := range cues {
+		result[i] = cue.Text
+	}
+	return result
+}
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name          string
+		cues          []Cue
+		autoGenerated bool
+		want          []string
+	}{
+		{
+			name:          "rolling captions",
+			cues:          cues("in the name of Allah", "of Allah the most merciful", "the most merciful the most kind"),
+			autoGenerated: true,
+			want:          []string{"in the name of Allah", "the most merciful", "the most kind"},
+		},
+		{
+			name:          "single word overlap",
+			cues:          cues("then we said Allah", "Allah is the greatest"),
+			autoGenerated: true,
+			want:          []string{"then we said Allah", "is the greatest"},
+		},
+		{
+			// cues 9 and 10 of docs/videos.json
+			name: "repeated word",
+			cues: cues(
+				"bismillah ar-Rahman ar-Raheem alhamdulillah wassalatu wassalamu ala rasulillah",
+				"ala ala alihi wa sahbihi wa man wala allahumma salli wassalam ala sayyidina muhammad al-fatiha",
+			),
+			autoGenerated: true,
+			want: []string{
+				"bismillah ar-Rahman ar-Raheem alhamdulillah wassalatu wassalamu ala rasulillah",
+				"ala alihi wa sahbihi wa man wala allahumma salli wassalam ala sayyidina muhammad al-fatiha",
+			},
+		},
+		{
+			name:          "repeated phrase",
+			cues:          cues("sallallahu ala alihi ala alihi wa sallam"),
+			autoGenerated: true,
+			want:          []string{"sallallahu ala alihi wa sallam"},
+		},
+		{
+			name: "written repeated word",
+			cues: cues("no no I had had enough"),
+			want: []string{"no no I had had enough"},
+		},
+		{
+			name: "written captions",
+			cues: cues("of Allah the most merciful", "the most merciful the most merciful"),
+			want: []string{"of Allah the most merciful", "the most merciful the most merciful"},
+		},
+		{
+			name: "whitespace and empty cues",
+			cues: cues("  as-salamu   alaykum ", "", "  "),
+			want: []string{"as-salamu alaykum"},
+		},
+		{
+			name: "unordered and duplicated cues",
+			cues: []Cue{
+				{Start: 2 * time.Second, End: 4 * time.Second, Text: "second"},
+				{Start: 0, End: 3 * time.Second, Text: "first"},
+				{Start: 2 * time.Second, End: 3 * time.Second, Text: "duplicate"},
+			},
+			want: []string{"first", "second"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original := slices.Clone(test.cues)
+			cleaned := Clean(test.cues, test.autoGenerated)
+			if got := texts(cleaned); !slices.Equal(got, test.want) {
+				t.Errorf("Clean = %q, want %q", got, test.want)
+			}
+			if !slices.Equal(test.cues, original) {
+				t.Errorf("Clean modified its input")
+			}
+			for i := 1; i < len(cleaned); i++ {
+				if cleaned[i].Start < cleaned[i-1].End {
+					t.Errorf("cue %d starts at %s before the previous one ends at %s", i, cleaned[i].Start, cleaned[i-1].End)
+				}
+			}
+		})
+	}
+}
+
+func TestQuality(t *testing.T) {
+	written := cues("In the name of Allah.", "The most merciful, the most kind.")
+	rolling := cues("in the name of Allah", "of Allah the most merciful", "", "the most merciful the most kind")
+	gaps := []Cue{
+		{Start: 0, End: time.Second, Text: "In the name of Allah."},
+		{Start: 9 * time.Second, End: 10 * time.Second, Text: "The most merciful."},
+	}
+	tests := []struct {
+		name          string
+		cues          []Cue
+		autoGenerated bool
+		want          float64
+	}{
+		{"written", written, false, 1},
+		{"rolling", rolling, true, 0.58},
+		{"gaps", gaps, false, 0.76},
+		{"empty", cues("", " "), false, 0},
+		{"none", nil, false, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Quality(test.cues, Clean(test.cues, test.autoGenerated))
+			if got != test.want {
+				t.Errorf("Quality = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// matches tags such as <c>, </c>, <i> and the inline word timings
+// <00:00:01.234> found in subtitles generated by youtube
+var tagRegex = regexp.MustCompile(`<[^>]*>`)
+
+var inlineTimingRegex = regexp.MustCompile(`<\d{2}:\d{2}:\d{2}\.\d{3}>`)
+
+// ParseSRT parses SubRip subtitles
+func ParseSRT(r io.Reader) ([]Cue, error) {
+	return parseCueBlocks(r)
+}
+
+// ParseVTT parses WebVTT subtitles. The second return value reports whether
+// the file contains inline word timings, which youtube only adds to
+// automatically generated captions
+func ParseVTT(r io.Reader) ([]Cue, bool, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, false, err
+	}
+	if !strings.HasPrefix(strings.TrimPrefix(string(data), "\ufeff"), "WEBVTT") {
+		return nil, false, fmt.Errorf("missing WEBVTT header")
+	}
+	autoGenerated := inlineTimingRegex.Match(data)
+	cues, err := parseCueBlocks(strings.NewReader(string(data)))
+	return cues, autoGenerated, err
+}
+
+// parseCueBlocks parses the blank line separated cue blocks shared by SRT
+// and VTT. Lines before the timing line of a block (cue numbers, ids and the
+// VTT header) are ignored
+func parseCueBlocks(r io.Reader) ([]Cue, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var cues []Cue
+	var current *Cue
+	var text []string
+	flush := func() {
+		if current != nil {
+			current.Text = cleanCueText(strings.Join(text, " "))
+			cues = append(cues, *current)
+		}
+		current = nil
+		text = nil
+	}
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		// only an empty line ends a cue, youtube captions contain lines
+		// with a single space inside a cue
+		raw := strings.TrimRight(scanner.Text(), "\r")
+		if raw == "" {
+			flush()
+			continue
+		}
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if strings.Contains(line, "-->") {
+			flush()
+			start, end, err := parseTimingLine(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			current = &Cue{Start: start, End: end}
+			continue
+		}
+		// text outside of a cue is a cue number, a VTT cue id or VTT header
+		// and metadata blocks such as NOTE and STYLE
+		if current == nil {
+			continue
+		}
+		text = append(text, line)
+	}
+	flush()
+	return cues, scanner.Err()
+}
+
+func parseTimingLine(line string) (time.Duration, time.Duration, error) {
+	start, rest, _ := strings.Cut(line, "-->")
+	// VTT cue settings such as "align:start position:0%" follow the end time
+	endFields := strings.Fields(rest)
+	if len(endFields) == 0 {
+		return 0, 0, fmt.Errorf("missing end time in %q", line)
+	}
+	startTime, err := parseTimestamp(strings.TrimSpace(start))
+	if err != nil {
+		return 0, 0, err
+	}
+	endTime, err := parseTimestamp(endFields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	return startTime, endTime, nil
+}
+
+// parseTimestamp parses "HH:MM:SS,mmm" (SRT) as well as "HH:MM:SS.mmm" and
+// "MM:SS.mmm" (VTT)
+func parseTimestamp(s string) (time.Duration, error) {
+	s = strings.Replace(s, ",", ".", 1)
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	total := time.Duration(seconds * float64(time.Second))
+	multiplier := time.Minute
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		total += time.Duration(n) * multiplier
+		multiplier *= 60
+	}
+	return total.Round(time.Millisecond), nil
+}
+
+type timedTextXML struct {
+	// legacy format: <transcript><text start="1.2" dur="3.4">...</text>
+	Texts []struct {
+		Start float64 `xml:"start,attr"`
+		Dur   float64 `xml:"dur,attr"`
+		Text  string  `xml:",chardata"`
+	} `xml:"text"`
+	// srv3 format: <timedtext format="3"><body><p t="1200" d="3400">...</p>
+	Paragraphs []struct {
+		T     int64  `xml:"t,attr"`
+		D     int64  `xml:"d,attr"`
+		Inner string `xml:",innerxml"`
+	} `xml:"body>p"`
+}
+
+// ParseTimedText parses the XML returned by youtube's timedtext endpoint in
+// either the legacy or the srv3 format
+func ParseTimedText(r io.Reader) ([]Cue, error) {
+	doc := timedTextXML{}
+	err := xml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	cues := make([]Cue, 0, len(doc.Texts)+len(doc.Paragraphs))
+	for _, text := range doc.Texts {
+		start := time.Duration(text.Start * float64(time.Second)).Round(time.Millisecond)
+		cues = append(cues, Cue{
+			Start: start,
+			End:   start + time.Duration(text.Dur*float64(time.Second)).Round(time.Millisecond),
+			// the legacy format escapes entities twice
+			Text: cleanCueText(html.UnescapeString(text.Text)),
+		})
+	}
+	for _, p := range doc.Paragraphs {
+		start := time.Duration(p.T) * time.Millisecond
+		cues = append(cues, Cue{
+			Start: start,
+			End:   start + time.Duration(p.D)*time.Millisecond,
+			Text:  cleanCueText(p.Inner),
+		})
+	}
+	return cues, nil
+}
+
+type whisperJSON struct {
+	Language string `json:"language"`
+	Segments []struct {
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+		Text  string  `json:"text"`
+	} `json:"segments"`
+}
+
+// ParseWhisperJSON parses the JSON output of openai-whisper and compatible
+// implementations. The detected language is returned with the cues
+func ParseWhisperJSON(r io.Reader) ([]Cue, string, error) {
+	doc := whisperJSON{}
+	err := json.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, "", err
+	}
+	cues := make([]Cue, 0, len(doc.Segments))
+	for _, segment := range doc.Segments {
+		cues = append(cues, Cue{
+			Start: time.Duration(segment.Start * float64(time.Second)).Round(time.Millisecond),
+			End:   time.Duration(segment.End * float64(time.Second)).Round(time.Millisecond),
+			Text:  cleanCueText(segment.Text),
+		})
+	}
+	return cues, doc.Language, nil
+}
+
+// cleanCueText removes markup and entities and collapses whitespace
+func cleanCueText(text string) string {
+	text = tagRegex.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,217 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/bevane/safina-society-search/internal/model"
+)
+
+// YtDlpProvider reads subtitle files downloaded with yt-dlp, for example with
+// `yt-dlp --write-subs --write-auto-subs --sub-langs en --skip-download`,
+// which are named <video id>.<language>.vtt or .srt
+type YtDlpProvider struct {
+	Dir string
+}
+
+func (p YtDlpProvider) Name() string {
+	return "yt-dlp"
+}
+
+func (p YtDlpProvider) Fetch(ctx context.Context, videoId string, language string) (Transcript, error) {
+	transcript := Transcript{VideoId: videoId, Language: language, Provider: p.Name()}
+	base := filepath.Join(p.Dir, fmt.Sprintf("%s.%s", videoId, language))
+
+	f, err := os.Open(base + ".vtt")
+	if err == nil {
+		defer f.Close()
+		cues, autoGenerated, err := ParseVTT(f)
+		if err != nil {
+			return Transcript{}, fmt.Errorf("%s.vtt: %w", base, err)
+		}
+		transcript.Cues = cues
+		transcript.AutoGenerated = autoGenerated
+		return transcript, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return Transcript{}, err
+	}
+
+	f, err = os.Open(base + ".srt")
+	if errors.Is(err, os.ErrNotExist) {
+		return Transcript{}, ErrNotFound
+	}
+	if err != nil {
+		return Transcript{}, err
+	}
+	defer f.Close()
+	cues, err := ParseSRT(f)
+	if err != nil {
+		return Transcript{}, fmt.Errorf("%s.srt: %w", base, err)
+	}
+	transcript.Cues = cues
+	return transcript, nil
+}
+
+// WhisperProvider reads transcripts generated locally with whisper, named
+// <video id>.<language>.json (whisper's JSON output) or .srt. Files named
+// <video id>.json or .srt without a language are only used for the language
+// whisper detected, or for model.DefaultLanguage if the language is not
+// known, so that one transcript is not stored as every language requested.
+// Whisper output is always treated as automatically generated
+type WhisperProvider struct {
+	Dir string
+}
+
+func (p WhisperProvider) Name() string {
+	return "whisper"
+}
+
+func (p WhisperProvider) Fetch(ctx context.Context, videoId string, language string) (Transcript, error) {
+	transcript := Transcript{VideoId: videoId, Language: language, Provider: p.Name(), AutoGenerated: true}
+	cues, _, err := readWhisperFile(filepath.Join(p.Dir, fmt.Sprintf("%s.%s", videoId, language)))
+	if err == nil {
+		transcript.Cues = cues
+		return transcript, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return Transcript{}, err
+	}
+
+	cues, detected, err := readWhisperFile(filepath.Join(p.Dir, videoId))
+	if err != nil {
+		return Transcript{}, err
+	}
+	if detected == "" {
+		detected = model.DefaultLanguage
+	}
+	// a transcript in a different language than the one requested does not
+	// count as found
+	if detected != language {
+		return Transcript{}, ErrNotFound
+	}
+	transcript.Cues = cues
+	return transcript, nil
+}
+
+// readWhisperFile reads base.json or else base.srt and returns the language
+// whisper detected if the JSON output has one
+func readWhisperFile(base string) ([]Cue, string, error) {
+	f, err := os.Open(base + ".json")
+	if err == nil {
+		defer f.Close()
+		cues, detected, err := ParseWhisperJSON(f)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s.json: %w", base, err)
+		}
+		return cues, detected, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, "", err
+	}
+
+	f, err = os.Open(base + ".srt")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	cues, err := ParseSRT(f)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s.srt: %w", base, err)
+	}
+	return cues, "", nil
+}
+
+const defaultTimedTextURL = "https://www.youtube.com/api/timedtext"
+
+// TimedTextProvider downloads captions from youtube's timedtext endpoint.
+// Captions written by the uploader are preferred over automatically
+// generated ones
+type TimedTextProvider struct {
+	Client *http.Client
+	// defaults to youtube's timedtext endpoint, can be pointed at a server
+	// replaying recorded responses
+	BaseURL string
+}
+
+func (p TimedTextProvider) Name() string {
+	return "timedtext"
+}
+
+func (p TimedTextProvider) Fetch(ctx context.Context, videoId string, language string) (Transcript, error) {
+	for _, autoGenerated := range []bool{false, true} {
+		cues, err := p.fetchTrack(ctx, videoId, language, autoGenerated)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return Transcript{}, err
+		}
+		return Transcript{
+			VideoId:       videoId,
+			Language:      language,
+			Provider:      p.Name(),
+			AutoGenerated: autoGenerated,
+			Cues:          cues,
+		}, nil
+	}
+	return Transcript{}, ErrNotFound
+}
+
+func (p TimedTextProvider) fetchTrack(ctx context.Context, videoId string, language string, autoGenerated bool) ([]Cue, error) {
+	baseURL := p.BaseURL
+	if baseURL == "" {
+		baseURL = defaultTimedTextURL
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	params := url.Values{}
+	params.Set("v", videoId)
+	params.Set("lang", language)
+	if autoGenerated {
+		params.Set("kind", "asr")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s for %s", res.Status, videoId)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	// the endpoint responds with an empty body when there is no track
+	if len(body) == 0 {
+		return nil, ErrNotFound
+	}
+	cues, err := ParseTimedText(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if len(cues) == 0 {
+		return nil, ErrNotFound
+	}
+	return cues, nil
+}
@@ -0,0 +1,139 @@
+package transcript
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const fixturesDir = "../../docs/fixtures"
+
+// fetch returns the text of the first cue of the transcript, or the error
+func fetch(t *testing.T, provider Provider, videoId string, language string) (string, error) {
+	t.Helper()
+	transcript, err := provider.Fetch(context.Background(), videoId, language)
+	if err != nil {
+		return "", err
+	}
+	if transcript.Language != language || transcript.Provider != provider.Name() {
+		t.Errorf("transcript of %s is labeled %s from %s", language, transcript.Language, transcript.Provider)
+	}
+	if len(transcript.Cues) == 0 {
+		t.Fatalf("%s transcript of %s has no cues", language, videoId)
+	}
+	return transcript.Cues[0].Text, nil
+}
+
+func TestYtDlpProvider(t *testing.T) {
+	provider := YtDlpProvider{Dir: filepath.Join(fixturesDir, "subs")}
+	en, err := fetch(t, provider, "AbCdEfGhIj0", "en")
+	if err != nil || !strings.Contains(strings.ToLower(en), "bismillah") {
+		t.Errorf("en = %q, %v", en, err)
+	}
+	ar, err := fetch(t, provider, "AbCdEfGhIj0", "ar")
+	if err != nil || !strings.Contains(ar, "بسم الله") {
+		t.Errorf("ar = %q, %v", ar, err)
+	}
+	_, err = fetch(t, provider, "AbCdEfGhIj0", "ur")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("ur err = %v, want ErrNotFound", err)
+	}
+	_, err = fetch(t, provider, "missing0000", "en")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing video err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestWhisperProviderJSON(t *testing.T) {
+	provider := WhisperProvider{Dir: filepath.Join(fixturesDir, "whisper")}
+	transcript, err := provider.Fetch(context.Background(), "AbCdEfGhIj0", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !transcript.AutoGenerated || len(transcript.Cues) != 2 {
+		t.Errorf("transcript = %+v, want 2 automatically generated cues", transcript)
+	}
+	// the fixture was detected as english
+	for _, language := range []string{"ar", "ur"} {
+		_, err = provider.Fetch(context.Background(), "AbCdEfGhIj0", language)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s err = %v, want ErrNotFound", language, err)
+		}
+	}
+}
+
+func TestWhisperProviderSRT(t *testing.T) {
+	dir := t.TempDir()
+	srt := "1\n00:00:00,000 --> 00:00:02,000\n%s\n"
+	writeFile(t, filepath.Join(dir, "video000001.srt"), strings.ReplaceAll(srt, "%s", "english"))
+	writeFile(t, filepath.Join(dir, "video000002.ar.srt"), strings.ReplaceAll(srt, "%s", "عربي"))
+	provider := WhisperProvider{Dir: dir}
+
+	tests := []struct {
+		videoId  string
+		language string
+		want     string
+	}{
+		// a transcript without a language is only used for the default
+		// language
+		{"video000001", "en", "english"},
+		{"video000001", "ar", ""},
+		{"video000001", "ur", ""},
+		{"video000002", "ar", "عربي"},
+		{"video000002", "en", ""},
+	}
+	for _, tt := range tests {
+		got, err := fetch(t, provider, tt.videoId, tt.language)
+		if tt.want == "" {
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s %s err = %v, want ErrNotFound", tt.videoId, tt.language, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s %s = %q, %v, want %q", tt.videoId, tt.language, got, err, tt.want)
+		}
+	}
+}
+
+func TestTimedTextProvider(t *testing.T) {
+	// replays the recorded response of the endpoint for the fixture video
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		if params.Get("kind") != "" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join(fixturesDir, "timedtext", params.Get("v")+"."+params.Get("lang")+".xml"))
+	}))
+	defer server.Close()
+	provider := TimedTextProvider{Client: server.Client(), BaseURL: server.URL}
+
+	transcript, err := provider.Fetch(context.Background(), "AbCdEfGhIj0", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transcript.AutoGenerated || len(transcript.Cues) != 3 {
+		t.Errorf("transcript = %+v, want 3 cues written by the uploader", transcript)
+	}
+	if got := transcript.Cues[2].Text; got != "today we talk about patience & what it means" {
+		t.Errorf("entities are not decoded: %q", got)
+	}
+	_, err = provider.Fetch(context.Background(), "AbCdEfGhIj0", "ar")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("ar err = %v, want ErrNotFound", err)
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
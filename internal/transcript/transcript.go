@@ -0,0 +1,83 @@
+// Package transcript obtains video transcripts from different sources and
+// normalizes them into a list of timed cues.
+package transcript
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by a provider that has no transcript for a video
+var ErrNotFound = errors.New("transcript not found")
+
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+type Transcript struct {
+	VideoId  string
+	Language string
+	// name of the provider the transcript was obtained from
+	Provider string
+	// true if the captions were generated by speech recognition rather
+	// than written by a person
+	AutoGenerated bool
+	Cues          []Cue
+}
+
+// SRT formats the cues the way transcripts are stored in the search index
+func (t Transcript) SRT() string {
+	var sb strings.Builder
+	for i, cue := range t.Cues {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n %s\n\n", i+1, formatSRTTimestamp(cue.Start), formatSRTTimestamp(cue.End), cue.Text)
+	}
+	return sb.String()
+}
+
+type Provider interface {
+	Name() string
+	// Fetch returns ErrNotFound if the provider has no transcript for the
+	// video in the requested language
+	Fetch(ctx context.Context, videoId string, language string) (Transcript, error)
+}
+
+// Chain tries each provider in order and returns the first transcript found
+type Chain []Provider
+
+func (c Chain) Name() string {
+	names := make([]string, len(c))
+	for i, provider := range c {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func (c Chain) Fetch(ctx context.Context, videoId string, language string) (Transcript, error) {
+	for _, provider := range c {
+		transcript, err := provider.Fetch(ctx, videoId, language)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return Transcript{}, fmt.Errorf("%s: %w", provider.Name(), err)
+		}
+		return transcript, nil
+	}
+	return Transcript{}, ErrNotFound
+}
+
+// FormatTimestamp formats an offset in a video as hh:mm:ss
+func FormatTimestamp(offset time.Duration) string {
+	seconds := int(offset.Seconds())
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+func formatSRTTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -8,7 +8,9 @@ package views
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
-func QuickStart() templ.Component {
+import "github.com/bevane/safina-society-search/internal/model"
+
+func QuickStart(collection model.Collection) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -29,7 +31,61 @@ func QuickStart() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<ul id=\"quick-start\"><li><div class=\"description\">Search a word</div><a class=\"example\" href=\"/search?q=Ashura&amp;page=1\">Ashura</a></li><li><div class=\"description\">Search a phrase</div><a class=\"example\" href=\"/search?q=Tenth of Muharram&amp;page=1\">Tenth of Muharram</a></li><li><div class=\"description\">Search for an exact match<br>with double quotes around search term</div><a class=\"example\" href=\"/search?q=&#34;AI&#34;&amp;page=1\">\"AI\"</a></li><li><div class=\"description\">Search within a specifc video<br>with video ID + search term</div><a class=\"example\" href=\"/search?q=KwRUYjugvpk Arafah&amp;page=1\">KwRUYjugvpk Arafah</a></li></ul>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<ul id=\"quick-start\"><li><div class=\"description\">Search a word</div><a class=\"example\" href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var2 templ.SafeURL = templ.URL(exampleUrl(collection, "Ashura"))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var2)))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\">Ashura</a></li><li><div class=\"description\">Search a phrase</div><a class=\"example\" href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var3 templ.SafeURL = templ.URL(exampleUrl(collection, "Tenth of Muharram"))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var3)))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\">Tenth of Muharram</a></li><li><div class=\"description\">Search for an exact match<br>with double quotes around search term</div><a class=\"example\" href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var4 templ.SafeURL = templ.URL(exampleUrl(collection, "\"AI\""))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var4)))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "\">\"AI\"</a></li><li><div class=\"description\">Search within a specifc video<br>with video ID + search term</div><a class=\"example\" href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var5 templ.SafeURL = templ.URL(exampleUrl(collection, "KwRUYjugvpk Arafah"))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var5)))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\">KwRUYjugvpk Arafah</a></li><li><div class=\"description\">Search part of a video<br>with id: + video ID + time range</div><a class=\"example\" href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var6 templ.SafeURL = templ.URL(exampleUrl(collection, "id:KwRUYjugvpk 40m-50m"))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var6)))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "\">id:KwRUYjugvpk 40m-50m</a></li><li><div class=\"description\">Browse videos without a search term</div><a class=\"example\" href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var7 templ.SafeURL = templ.URL(collection.TopicsPath())
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var7)))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "\">Topics</a></li></ul>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -37,4 +93,8 @@ func QuickStart() templ.Component {
 	})
 }
 
+func exampleUrl(collection model.Collection, query string) string {
+	return collection.SearchUrl(model.SearchState{Query: query, Page: 1})
+}
+
 var _ = templruntime.GeneratedTemplate
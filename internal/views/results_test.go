@@ -0,0 +1,25 @@
+package views
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bevane/safina-society-search/internal/model"
+)
+
+func TestHighlightedEscapes(t *testing.T) {
+	text := model.HighlightedText{
+		{Text: `<script>alert("1")</script> &amp; `},
+		{Text: "<b>patience</b>", Marked: true},
+	}
+	var sb strings.Builder
+	err := highlighted(text).Render(context.Background(), &sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `&lt;script&gt;alert(&#34;1&#34;)&lt;/script&gt; &amp;amp; <mark>&lt;b&gt;patience&lt;/b&gt;</mark>`
+	if sb.String() != want {
+		t.Errorf("highlighted rendered %s, want %s", sb.String(), want)
+	}
+}
@@ -4,11 +4,18 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/a-h/templ"
+	"github.com/bevane/safina-society-search/internal/alerts"
+	"github.com/bevane/safina-society-search/internal/analytics"
+	"github.com/bevane/safina-society-search/internal/bookmarks"
+	"github.com/bevane/safina-society-search/internal/embedding"
+	"github.com/bevane/safina-society-search/internal/model"
 	"github.com/bevane/safina-society-search/internal/views"
 	"github.com/joho/godotenv"
 	"github.com/meilisearch/meilisearch-go"
@@ -16,16 +23,49 @@ import (
 
 type Config struct {
 	searchClient meilisearch.ServiceManager
-	port         int
+	// the first collection is the default collection
+	collections []model.Collection
+	analytics   *analytics.Store
+	// nil if semantic search is not configured
+	embedder embedding.Embedder
+	port     int
+	// scheme and host the site is served at without a trailing slash, used
+	// where absolute urls are needed
+	siteUrl string
+	sitemap *sitemapCache
+	// nil if alerts are not configured
+	alerts    *alerts.Store
+	notifiers alerts.Notifiers
+	// limits the alerts each client can subscribe to, as every one sends a
+	// confirmation email
+	subscribeLimiter *rateLimiter
+	// proxies whose X-Forwarded-For header is used as the client address
+	trustedProxies []netip.Prefix
+	// sends the confirmation of email alerts, nil if SMTP is not configured
+	mailer alerts.Mailer
+	// nil if bookmarks are not configured
+	bookmarks *bookmarks.Store
 }
 
 func main() {
-	app := Config{}
+	// include the request id in every log record made with a request context
+	slog.SetDefault(slog.New(contextHandler{slog.NewTextHandler(os.Stderr, nil)}))
+
+	app := Config{sitemap: &sitemapCache{}, subscribeLimiter: newRateLimiter(subscribesPerHour, time.Hour)}
 	err := godotenv.Load(".env")
 	if err != nil {
 		slog.Info("No .env file available. Ensure the required env variables are set")
 	}
 	app.port, _ = strconv.Atoi(os.Getenv("PORT"))
+	app.siteUrl = strings.TrimSuffix(os.Getenv("SITE_URL"), "/")
+	if app.siteUrl == "" {
+		app.siteUrl = "https://safinasocietysearch.com"
+	}
+	app.trustedProxies, err = parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		slog.Error("unable to parse trusted proxies", slog.Any("error", err))
+		os.Exit(1)
+	}
 
 	searchClient, err := meilisearch.Connect(os.Getenv("MEILISEARCH_URL"), meilisearch.WithAPIKey(os.Getenv("MEILISEARCH_API_KEY")))
 	if err != nil {
@@ -35,15 +75,141 @@ func main() {
 	}
 	app.searchClient = searchClient
 
+	// semantic search is optional and only offered if word vectors are
+	// configured
+	if embeddingsFile := os.Getenv("EMBEDDINGS_FILE"); embeddingsFile != "" {
+		maxWords, err := strconv.Atoi(os.Getenv("EMBEDDINGS_MAX_WORDS"))
+		if err != nil {
+			maxWords = 100000
+		}
+		wordVectors, err := embedding.LoadWordVectors(embeddingsFile, maxWords)
+		if err != nil {
+			slog.Error("unable to load word vectors", slog.Any("error", err))
+			os.Exit(1)
+		}
+		app.embedder = wordVectors
+	}
+
+	app.collections, err = loadCollections(os.Getenv("COLLECTIONS_FILE"), app.embedder != nil)
+	if err != nil {
+		slog.Error("unable to load collections", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// alerts are optional and only saved if a file is configured, the
+	// store is opened before running commands as sync delivers them
+	if alertsFile := os.Getenv("ALERTS_FILE"); alertsFile != "" {
+		app.alerts, err = alerts.NewStore(alertsFile)
+		if err != nil {
+			slog.Error("unable to open alerts store", slog.Any("error", err))
+			os.Exit(1)
+		}
+		app.notifiers.Webhook = alerts.WebhookNotifier{Client: &http.Client{Timeout: 30 * time.Second}}
+		if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+			smtpPort, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+			if err != nil {
+				smtpPort = 587
+			}
+			app.mailer = alerts.NewSMTPMailer(smtpHost, smtpPort, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), os.Getenv("SMTP_FROM"))
+			app.notifiers.Email = alerts.EmailNotifier{Mailer: app.mailer}
+		}
+	}
+
+	// run a maintenance command such as `sync` instead of starting the server
+	if len(os.Args) > 1 {
+		err = runCommand(&app, os.Args[1], os.Args[2:])
+		if err != nil {
+			slog.Error(fmt.Sprintf("%s failed", os.Args[1]), slog.Any("error", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// analytics are optional and only recorded if a file is configured
+	if analyticsFile := os.Getenv("ANALYTICS_FILE"); analyticsFile != "" {
+		retentionDays, err := strconv.Atoi(os.Getenv("ANALYTICS_RETENTION_DAYS"))
+		if err != nil {
+			retentionDays = 90
+		}
+		store, err := analytics.NewStore(analyticsFile, time.Duration(retentionDays)*24*time.Hour)
+		if err != nil {
+			slog.Error("unable to open analytics store", slog.Any("error", err))
+			os.Exit(1)
+		}
+		err = store.Prune()
+		if err != nil {
+			slog.Error("unable to prune analytics store", slog.Any("error", err))
+		}
+		go store.PruneEvery(24*time.Hour, nil, func(err error) {
+			slog.Error("unable to prune analytics store", slog.Any("error", err))
+		})
+		app.analytics = store
+	}
+
+	// bookmarks are optional and only saved if a file is configured
+	if bookmarksFile := os.Getenv("BOOKMARKS_FILE"); bookmarksFile != "" {
+		app.bookmarks, err = bookmarks.NewStore(bookmarksFile)
+		if err != nil {
+			slog.Error("unable to open bookmarks store", slog.Any("error", err))
+			os.Exit(1)
+		}
+	}
+
 	serveMux := http.NewServeMux()
 	publicHandler := http.StripPrefix("/public", http.FileServer(http.Dir("./public")))
-	serveMux.Handle("/", templ.Handler(views.Index("", nil)))
+	serveMux.Handle("/", templ.Handler(views.Index(app.collections[0], model.SearchState{}, nil, app.pageMeta(app.collections[0], "/"))))
 	serveMux.Handle("/public/", publicHandler)
 	serveMux.HandleFunc("GET /search", app.handlerSearch)
+	serveMux.HandleFunc("GET /search.atom", app.handlerSearchFeed)
+	serveMux.HandleFunc("GET /c/{collection}", app.handlerCollection)
+	serveMux.HandleFunc("GET /c/{collection}/{$}", app.handlerCollection)
+	serveMux.HandleFunc("GET /c/{collection}/search", app.handlerSearch)
+	serveMux.HandleFunc("GET /c/{collection}/search.atom", app.handlerSearchFeed)
+	serveMux.HandleFunc("GET /topics", app.handlerTopics)
+	serveMux.HandleFunc("GET /c/{collection}/topics", app.handlerTopics)
+	serveMux.HandleFunc("GET /robots.txt", app.handlerRobots)
+	serveMux.HandleFunc("GET /sitemap.xml", app.handlerSitemapIndex)
+	serveMux.HandleFunc("GET /sitemaps/{chunk}", app.handlerSitemap)
+	serveMux.HandleFunc("GET /opensearch.xml", app.handlerOpenSearch)
+	serveMux.HandleFunc("GET /c/{collection}/opensearch.xml", app.handlerOpenSearch)
+	serveMux.HandleFunc("GET /suggest", app.handlerSuggest)
+	serveMux.HandleFunc("GET /c/{collection}/suggest", app.handlerSuggest)
+	serveMux.HandleFunc("GET /click", app.handlerClick)
+	serveMux.HandleFunc("GET /player", app.handlerPlayer)
+	serveMux.HandleFunc("GET /video/{id}", app.handlerVideo)
+	serveMux.HandleFunc("GET /video/{id}/related", app.handlerRelated)
+	serveMux.HandleFunc("POST /preferences", handlerPreferences)
+	// collections of bookmarked moments, not to be confused with the
+	// collections of channels under /c/
+	if app.bookmarks != nil {
+		serveMux.HandleFunc("GET /collections", app.handlerBookmarkLists)
+		serveMux.HandleFunc("GET /collections/save", app.handlerSaveMomentForm)
+		serveMux.HandleFunc("POST /collections/moments", app.handlerSaveMoment)
+		serveMux.HandleFunc("GET /collections/{id}", app.handlerBookmarkList)
+		serveMux.HandleFunc("GET /collections/{id}/export.md", app.handlerExportBookmarkList("md"))
+		serveMux.HandleFunc("GET /collections/{id}/export.json", app.handlerExportBookmarkList("json"))
+		serveMux.HandleFunc("POST /collections/{id}/remove", app.handlerRemoveMoment)
+		serveMux.HandleFunc("POST /collections/{id}/delete", app.handlerDeleteBookmarkList)
+	}
+	if app.alerts != nil {
+		serveMux.HandleFunc("GET /alerts/confirm", app.handlerConfirmAlert)
+		serveMux.HandleFunc("GET /alerts/unsubscribe", app.handlerConfirmUnsubscribe)
+		serveMux.HandleFunc("POST /alerts/unsubscribe", app.handlerUnsubscribe)
+		// users can only subscribe by email if confirmations can be sent
+		if app.mailer != nil {
+			serveMux.HandleFunc("POST /alerts", app.handlerSubscribe)
+			serveMux.HandleFunc("POST /c/{collection}/alerts", app.handlerSubscribe)
+		}
+	}
+	// the admin dashboard needs recorded analytics and a password to be set
+	if adminPassword := os.Getenv("ADMIN_PASSWORD"); adminPassword != "" && app.analytics != nil {
+		serveMux.Handle("GET /admin", basicAuth(adminPassword, http.HandlerFunc(app.handlerAdmin)))
+		serveMux.Handle("GET /admin/export.csv", basicAuth(adminPassword, http.HandlerFunc(app.handlerAdminExport)))
+	}
 	server := &http.Server{
 		Addr:              fmt.Sprintf(":%d", app.port),
 		ReadHeaderTimeout: 3 * time.Second,
-		Handler:           serveMux,
+		Handler:           requestID(preferences(serveMux)),
 	}
 	slog.Info(fmt.Sprintf("Server started on port %v\n", app.port))
 	err = server.ListenAndServe()
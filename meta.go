@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/views"
+)
+
+// pageMeta returns the preview of a page of the collection, path is the
+// path and query of the page
+func (cfg *Config) pageMeta(collection model.Collection, path string) model.PageMeta {
+	return model.PageMeta{
+		Title:       collection.ChannelName + " Search",
+		Description: collection.Description,
+		Url:         cfg.siteUrl + path,
+		ImageUrl:    cfg.siteUrl + "/public/preview.jpg",
+	}
+}
+
+// searchMeta returns the preview of the results of a search, showing the
+// query and the top result so a shared search does not look like the home
+// page
+func (cfg *Config) searchMeta(collection model.Collection, state model.SearchState, results model.Results) model.PageMeta {
+	meta := cfg.pageMeta(collection, collection.SearchUrl(state))
+	switch {
+	case state.Query != "":
+		meta.Title = fmt.Sprintf("“%s” - %s", state.Query, meta.Title)
+	case state.Topic != "":
+		meta.Title = fmt.Sprintf("Videos about %s - %s", state.Topic, meta.Title)
+	}
+	if results.FeedUrl != "" {
+		meta.FeedUrl = cfg.siteUrl + results.FeedUrl
+	}
+	if len(results.Items) == 0 {
+		return meta
+	}
+	top := results.Items[0]
+	if results.TotalHits == 1 {
+		meta.Description = fmt.Sprintf("Found in “%s”", top.Title.String())
+	} else {
+		meta.Description = fmt.Sprintf("Found in %d videos including “%s”", results.TotalHits, top.Title.String())
+	}
+	meta.ImageUrl = top.ThumbnailUrl
+	return meta
+}
+
+// number of words of the transcript that describe a video
+const excerptWords = 30
+
+// videoMeta returns the preview of the transcript page of a video, the
+// start of the transcript describes it
+func (cfg *Config) videoMeta(collection model.Collection, video model.Video, srt string) model.PageMeta {
+	meta := cfg.pageMeta(collection, views.VideoPath(video.Id, video.Language))
+	meta.Title = video.Title
+	meta.Description = videoDescription(collection, video.Title, srt)
+	meta.ImageUrl = video.ThumbnailUrl
+	return meta
+}
+
+// videoDescription describes a video by the first words of its transcript
+func videoDescription(collection model.Collection, title string, srt string) string {
+	excerpt := passageSnippet(srt, 0, excerptWords)
+	if excerpt == "" {
+		return fmt.Sprintf("Transcript of “%s” on %s", title, collection.ChannelName)
+	}
+	return excerpt + " …"
+}
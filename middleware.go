@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/model"
+)
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+const requestIDHeader = "X-Request-Id"
+
+// requestIDFromContext returns the request id assigned by the requestID
+// middleware or "" if the context did not come from an http request
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// randRead is replaced in tests to exercise the fallback request ids
+var randRead = rand.Read
+
+// fallbackRequestIDs counts the request ids made without crypto/rand
+var fallbackRequestIDs atomic.Uint64
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, err := randRead(b)
+	if err != nil {
+		// crypto/rand does not fail on supported platforms, but fall back to
+		// an id made of the time and a counter rather than leaving the
+		// request without one, the counter keeps requests served at the
+		// same time apart
+		return strconv.FormatInt(time.Now().UnixNano(), 16) + "-" + strconv.FormatUint(fallbackRequestIDs.Add(1), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// contextHandler adds the request id stored in the context to every log
+// record, so any slog.*Context call made while handling a request can be
+// tied back to the access log entry and to the id shown to the user
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("requestID", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// statusRecorder captures the status code written by a handler so it can
+// be included in the access log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// requestID assigns an id to every request, stores it in the request
+// context, echoes it back in the X-Request-Id response header and writes a
+// structured access log entry once the request has been served
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := newRequestID()
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		w.Header().Set(requestIDHeader, id)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		slog.InfoContext(ctx, "request served",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("query", r.URL.RawQuery),
+			slog.Int("status", rec.status),
+			slog.Bool("isHTMX", r.Header.Get("Hx-Request") != ""),
+			slog.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+// preferences stores the user's preferences of how videos are opened and
+// results are shown in the request context
+func preferences(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		preferences := model.Preferences{
+			PrivacyEnhanced: cookieSet(r, privacyCookie),
+			Inline:          cookieSet(r, inlinePlayerCookie),
+			InfiniteScroll:  cookieSet(r, infiniteScrollCookie),
+		}
+		next.ServeHTTP(w, r.WithContext(model.WithPreferences(r.Context(), preferences)))
+	})
+}
+
+// rateLimiter allows each client a number of requests in a window of time
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	// start of the current window of each client and the number of
+	// requests made in it
+	clients map[string]*clientWindow
+	pruned  time.Time
+}
+
+type clientWindow struct {
+	start    time.Time
+	requests int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, clients: map[string]*clientWindow{}}
+}
+
+// allow records a request of the client and reports whether it is within
+// the limit
+func (l *rateLimiter) allow(client string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// clients whose window ended are forgotten so that the map does not
+	// grow with every client ever seen
+	if now.Sub(l.pruned) > l.window {
+		for key, w := range l.clients {
+			if now.Sub(w.start) > l.window {
+				delete(l.clients, key)
+			}
+		}
+		l.pruned = now
+	}
+	w, ok := l.clients[client]
+	if !ok || now.Sub(w.start) > l.window {
+		w = &clientWindow{start: now}
+		l.clients[client] = w
+	}
+	w.requests++
+	return w.requests <= l.limit
+}
+
+// clientIP returns the ip address the request came from. Behind one of the
+// trusted proxies it is the address the proxies forwarded the request for,
+// the X-Forwarded-For header is ignored otherwise as clients can set it to
+// anything
+func (cfg *Config) clientIP(r *http.Request) string {
+	remote, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	client := remote.Addr().Unmap()
+	// each proxy appends the address it got the request from, so the
+	// client is the last address that is not one of the proxies
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0 && cfg.trustedProxy(client); i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+	}
+	return client.String()
+}
+
+func (cfg *Config) trustedProxy(addr netip.Addr) bool {
+	return slices.ContainsFunc(cfg.trustedProxies, func(prefix netip.Prefix) bool {
+		return prefix.Contains(addr)
+	})
+}
+
+// parseTrustedProxies parses a comma separated list of ip addresses and
+// networks such as "127.0.0.1,10.0.0.0/8"
+func parseTrustedProxies(text string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, field := range strings.Split(text, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, err
+			}
+			addr = addr.Unmap()
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(contextHandler{slog.NewJSONHandler(&logs, nil)}))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	var contextID string
+	handler := requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contextID = requestIDFromContext(r.Context())
+		slog.InfoContext(r.Context(), "handling")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?q=patience", nil))
+
+	headerID := w.Header().Get(requestIDHeader)
+	if headerID == "" || headerID != contextID {
+		t.Fatalf("header id %q, context id %q", headerID, contextID)
+	}
+	var records []map[string]any
+	for line := range bytes.Lines(logs.Bytes()) {
+		var record map[string]any
+		err := json.Unmarshal(line, &record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d log records, want the handler's and the access log", len(records))
+	}
+	for _, record := range records {
+		if record["requestID"] != headerID {
+			t.Errorf("record %v does not have request id %s", record, headerID)
+		}
+	}
+	if records[1]["status"] != float64(http.StatusTeapot) || records[1]["query"] != "q=patience" {
+		t.Errorf("access log %v", records[1])
+	}
+
+	// a log record made without a request has no id
+	logs.Reset()
+	slog.Info("startup")
+	if bytes.Contains(logs.Bytes(), []byte("requestID")) {
+		t.Errorf("record without a request has an id: %s", logs.String())
+	}
+}
+
+func TestNewRequestIDFallback(t *testing.T) {
+	t.Cleanup(func() { randRead = rand.Read })
+	randRead = func([]byte) (int, error) {
+		return 0, errors.New("no entropy")
+	}
+	ids := map[string]bool{}
+	for range 1000 {
+		id := newRequestID()
+		if id == "" || ids[id] {
+			t.Fatalf("fallback id %q is empty or repeated", id)
+		}
+		ids[id] = true
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(3, time.Hour)
+	now := time.Now()
+	for i := range 3 {
+		if !limiter.allow("192.0.2.1", now.Add(time.Duration(i)*time.Minute)) {
+			t.Errorf("request %d is not allowed", i+1)
+		}
+	}
+	if limiter.allow("192.0.2.1", now.Add(10*time.Minute)) {
+		t.Error("the request over the limit is allowed")
+	}
+	if !limiter.allow("192.0.2.2", now.Add(10*time.Minute)) {
+		t.Error("another client is limited")
+	}
+	if !limiter.allow("192.0.2.1", now.Add(61*time.Minute)) {
+		t.Error("the client is still limited in the next window")
+	}
+}
+
+func TestRateLimiterForgetsClients(t *testing.T) {
+	limiter := newRateLimiter(1, time.Minute)
+	now := time.Now()
+	for i := range 100 {
+		limiter.allow(fmt.Sprintf("192.0.2.%d", i), now)
+	}
+	limiter.allow("198.51.100.1", now.Add(2*time.Minute))
+	if len(limiter.clients) != 1 {
+		t.Errorf("the limiter remembers %d clients, want 1", len(limiter.clients))
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies(" 127.0.0.1, 10.0.0.0/8,::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{trustedProxies: proxies}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"direct", "192.0.2.1:1234", nil, "192.0.2.1"},
+		{"direct with a forged header", "192.0.2.1:1234", []string{"198.51.100.7"}, "192.0.2.1"},
+		{"trusted proxy", "127.0.0.1:1234", []string{"192.0.2.1"}, "192.0.2.1"},
+		{"ipv6 proxy", "[::1]:1234", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"ipv4 mapped proxy", "[::ffff:127.0.0.1]:1234", []string{"192.0.2.1"}, "192.0.2.1"},
+		{"chain of trusted proxies", "127.0.0.1:1234", []string{"192.0.2.1, 10.1.2.3"}, "192.0.2.1"},
+		{"address forged before the proxy", "127.0.0.1:1234", []string{"198.51.100.7, 192.0.2.1"}, "192.0.2.1"},
+		{"several headers", "127.0.0.1:1234", []string{"198.51.100.7", "192.0.2.1"}, "192.0.2.1"},
+		{"trusted proxy without a header", "127.0.0.1:1234", nil, "127.0.0.1"},
+		{"invalid forwarded address", "127.0.0.1:1234", []string{"unknown"}, "127.0.0.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/alerts", nil)
+			r.RemoteAddr = test.remoteAddr
+			for _, forwarded := range test.forwarded {
+				r.Header.Add("X-Forwarded-For", forwarded)
+			}
+			if got := cfg.clientIP(r); got != test.want {
+				t.Errorf("clientIP = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := parseTrustedProxies("")
+	if err != nil || len(proxies) != 0 {
+		t.Errorf("parseTrustedProxies(\"\") = %v, %v", proxies, err)
+	}
+	for _, text := range []string{"localhost", "10.0.0.0/33", "127.0.0.1:80"} {
+		_, err := parseTrustedProxies(text)
+		if err == nil {
+			t.Errorf("parseTrustedProxies(%q) did not fail", text)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// number of suggestions browsers show under the address bar
+const maxSuggestions = 8
+
+// opensearch short names are limited to 16 characters
+const maxShortNameLength = 16
+
+// openSearchDescription is the OpenSearch description document that lets
+// browsers add a collection as a search engine
+// see https://github.com/dewitt/opensearch
+type openSearchDescription struct {
+	XMLName       xml.Name        `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+	ShortName     string          `xml:"ShortName"`
+	Description   string          `xml:"Description"`
+	InputEncoding string          `xml:"InputEncoding"`
+	Image         openSearchImage `xml:"Image"`
+	Urls          []openSearchUrl `xml:"Url"`
+}
+
+type openSearchImage struct {
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+	Type   string `xml:"type,attr"`
+	Url    string `xml:",chardata"`
+}
+
+type openSearchUrl struct {
+	Type     string `xml:"type,attr"`
+	Method   string `xml:"method,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// handlerOpenSearch serves the OpenSearch description of the collection,
+// searches from the browser go to the search page and suggestions to the
+// suggest endpoint
+func (cfg *Config) handlerOpenSearch(w http.ResponseWriter, r *http.Request) {
+	collection, ok := cfg.collectionFromRequest(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	shortName := collection.ChannelName
+	for utf8.RuneCountInString(shortName) > maxShortNameLength {
+		_, size := utf8.DecodeLastRuneInString(shortName)
+		shortName = shortName[:len(shortName)-size]
+	}
+	description := openSearchDescription{
+		ShortName:     shortName,
+		Description:   collection.Description,
+		InputEncoding: "UTF-8",
+		Image: openSearchImage{
+			Width:  16,
+			Height: 16,
+			Type:   "image/x-icon",
+			Url:    cfg.siteUrl + "/public/favicon.ico",
+		},
+		Urls: []openSearchUrl{
+			{
+				Type:     "text/html",
+				Method:   "get",
+				Template: cfg.siteUrl + collection.SearchPath() + "?q={searchTerms}&page=1",
+			},
+			{
+				Type:     "application/x-suggestions+json",
+				Method:   "get",
+				Template: cfg.siteUrl + collection.SuggestPath() + "?q={searchTerms}",
+			},
+		},
+	}
+	w.Header().Set("Content-Type", "application/opensearchdescription+xml")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	_, err := w.Write([]byte(xml.Header))
+	if err == nil {
+		err = xml.NewEncoder(w).Encode(description)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write opensearch description", slog.Any("error", err))
+	}
+}
+
+// handlerSuggest returns the titles of the videos matching the query in
+// the OpenSearch suggestions format: ["query", ["title", ...]]
+func (cfg *Config) handlerSuggest(w http.ResponseWriter, r *http.Request) {
+	collection, ok := cfg.collectionFromRequest(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query().Get(model.ParamQuery)
+	suggestions := []string{}
+	// same minimum length as the search page
+	if len(query) > 2 {
+		var err error
+		suggestions, err = getSuggestions(r.Context(), cfg.searchedCollections(collection), query, cfg.searchClient)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "unable to get suggestions", slog.Any("error", err))
+		}
+	}
+	w.Header().Set("Content-Type", "application/x-suggestions+json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	err := json.NewEncoder(w).Encode([]any{query, suggestions})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write suggestions", slog.Any("error", err))
+	}
+}
+
+// getSuggestions returns the titles of the videos whose title best matches
+// the query, from each collection in turn until there are maxSuggestions
+func getSuggestions(ctx context.Context, collections []model.Collection, query string, searchClient meilisearch.ServiceManager) ([]string, error) {
+	suggestions := []string{}
+	for _, collection := range collections {
+		searchRequest := meilisearch.SearchRequest{
+			AttributesToSearchOn: []string{"title"},
+			AttributesToRetrieve: []string{"title"},
+			Limit:                maxSuggestions,
+		}
+		response, err := searchIndex(ctx, collection.IndexName, query, &searchRequest, searchClient)
+		if err != nil {
+			return suggestions, err
+		}
+		for _, hit := range response.Hits {
+			if len(suggestions) == maxSuggestions {
+				return suggestions, nil
+			}
+			if !slices.Contains(suggestions, hit.Title) {
+				suggestions = append(suggestions, hit.Title)
+			}
+		}
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/link"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/transcript"
+	"github.com/bevane/safina-society-search/internal/views"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// handlerPlayer renders the embedded player of a video playing from a
+// moment for the player panel of the search page, with the moments the
+// query matches in the video to seek to. It takes the same params as the
+// click endpoint and records the click when opened from a result
+func (cfg *Config) handlerPlayer(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	videoId := params.Get("v")
+	if !link.ValidVideoId(videoId) {
+		http.Error(w, "invalid video id", http.StatusBadRequest)
+		return
+	}
+	start, err := strconv.Atoi(params.Get("t"))
+	if params.Get("t") != "" && (err != nil || start < 0) {
+		http.Error(w, "invalid timestamp", http.StatusBadRequest)
+		return
+	}
+	state := resultState(params)
+	if position, _ := strconv.Atoi(params.Get("pos")); position > 0 {
+		cfg.recordClick(r.Context(), state, videoId, position)
+	}
+
+	document, collection, err := cfg.findVideo(r.Context(), videoId, []string{"id", "title"})
+	if errors.Is(err, errVideoNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to get video", slog.String("videoId", videoId), slog.Any("error", err))
+		err = views.InternalError(requestIDFromContext(r.Context())).Render(r.Context(), w)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "unable to render error component", slog.Any("error", err))
+		}
+		return
+	}
+	// the player still plays the video if the matches are unknown
+	moments, err := cfg.matchMoments(r.Context(), collection, videoId, state)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to get the moments of the matches", slog.String("videoId", videoId), slog.Any("error", err))
+	}
+
+	video := link.Video{Id: videoId, Start: start, Captions: state.Lang}
+	player := model.Player{
+		VideoId: videoId,
+		Title:   document.Title,
+		Url:     video.Watch(),
+		Current: -1,
+	}
+	if cfg.bookmarks != nil {
+		player.SaveUrl = saveMomentPath(videoId, strconv.Itoa(start), state.Lang)
+	}
+	video.Autoplay = true
+	player.EmbedUrl = video.Embed(model.PreferencesFromContext(r.Context()).PrivacyEnhanced)
+	for i, moment := range moments {
+		seconds := int(moment.Seconds())
+		momentUrl := playerUrl(state, videoId, strconv.Itoa(seconds), 0)
+		player.Moments = append(player.Moments, model.Moment{
+			Timestamp: transcript.FormatTimestamp(moment),
+			PlayerUrl: momentUrl,
+		})
+		switch {
+		case seconds < start:
+			player.PreviousUrl = momentUrl
+		case seconds == start:
+			player.Current = i
+		case player.NextUrl == "":
+			player.NextUrl = momentUrl
+		}
+	}
+
+	err = views.Player(player).Render(r.Context(), w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to render player", slog.Any("error", err))
+	}
+}
+
+// matchMoments returns the start of every cue of the video that
+// meilisearch matches the query in, in order, so that the moments are the
+// matches of the search including typos and prefixes. For a query within a
+// video only the cues in its time range are kept
+func (cfg *Config) matchMoments(ctx context.Context, collection model.Collection, videoId string, state model.SearchState) ([]time.Duration, error) {
+	query := state.Query
+	var start, end time.Duration
+	if vq, ok := parseVideoQuery(query); ok {
+		query = strings.Join(vq.Terms, " ")
+		start, end = vq.Start, vq.End
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	transcriptField := model.TranscriptField(state.Lang)
+	searchRequest := meilisearch.SearchRequest{
+		AttributesToSearchOn: []string{transcriptField},
+		AttributesToRetrieve: []string{"id", transcriptField},
+		ShowMatchesPosition:  true,
+		Limit:                1,
+	}
+	filterSearch(&searchRequest, model.SearchState{Lang: state.Lang}, idFilter([]string{videoId}))
+	response, err := searchIndex(ctx, collection.IndexName, query, &searchRequest, cfg.searchClient)
+	if err != nil || len(response.Hits) == 0 {
+		return nil, err
+	}
+
+	hit := response.Hits[0]
+	lines := transcriptLines(hit.TranscriptIn(state.Lang))
+	var moments []time.Duration
+	for _, match := range hit.MatchesPosition.TranscriptIn(state.Lang) {
+		// the line the match is in, matches in timing lines are skipped
+		i := sort.Search(len(lines), func(i int) bool {
+			return lines[i].end > match.Start
+		})
+		if i == len(lines) || match.Start < lines[i].start {
+			continue
+		}
+		line := lines[i]
+		// cues that end before the range starts or start after it ends
+		if (line.cueStart < start && line.cueEnd <= start) || (end != 0 && line.cueStart >= end) {
+			continue
+		}
+		moments = append(moments, line.cueStart)
+	}
+	// links are to the second, so cues starting in the same second are the
+	// same moment
+	slices.Sort(moments)
+	return slices.Compact(moments), nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+const playerTranscript = "1\n00:00:00,000 --> 00:00:05,000\nin the name of Allah\n\n" +
+	"2\n00:00:05,000 --> 00:01:05,000\nbe patient with them\n\n" +
+	"3\n00:01:05,000 --> 00:01:10,000\nhave patience in hardship\n\n" +
+	"4\n00:02:00,000 --> 00:02:05,000\npatience patience\n"
+
+// playerMeilisearch answers with the video and the matches meilisearch
+// would find for "patiense", including the typo and prefix matches an
+// exact match of the terms misses
+func playerMeilisearch(t *testing.T, wantQuery string) meilisearch.ServiceManager {
+	t.Helper()
+	var matches []map[string]int
+	for _, word := range []string{"patient ", "patience in", "patience patience"} {
+		start := strings.Index(playerTranscript, word)
+		matches = append(matches, map[string]int{"start": start, "length": len(strings.Fields(word)[0])})
+	}
+	last := strings.LastIndex(playerTranscript, "patience")
+	matches = append(matches,
+		map[string]int{"start": last, "length": len("patience")},
+		// meilisearch can match the numbers of a timing line
+		map[string]int{"start": 2, "length": 2},
+	)
+	return fakeMeilisearch(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/indexes/videos/documents/AbCdEfGhIj0":
+			writeJSON(w, map[string]any{"id": "AbCdEfGhIj0", "title": "Patience"})
+		case "/indexes/videos/search":
+			var body meilisearch.SearchRequest
+			decodeBody(t, r, &body)
+			if body.Query != wantQuery || body.Filter != `id IN ["AbCdEfGhIj0"]` {
+				t.Errorf("searched %q with filter %v, want %q in the video", body.Query, body.Filter, wantQuery)
+			}
+			writeJSON(w, map[string]any{"hits": []map[string]any{{
+				"id":               "AbCdEfGhIj0",
+				"transcript":       playerTranscript,
+				"_matchesPosition": map[string]any{"transcript": matches},
+			}}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func TestMatchMoments(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantQuery string
+		want      []time.Duration
+	}{
+		{"patiense", "patiense", []time.Duration{5 * time.Second, 65 * time.Second, 120 * time.Second}},
+		// the cue starting at 5s ends within the range
+		{"id:AbCdEfGhIj0 1m-2m patiense", "patiense", []time.Duration{5 * time.Second, 65 * time.Second}},
+		{"id:AbCdEfGhIj0 1m-2m", "", nil},
+	}
+	for _, test := range tests {
+		cfg := &Config{searchClient: playerMeilisearch(t, test.wantQuery)}
+		collection := model.Collection{Id: "safina", IndexName: "videos"}
+		moments, err := cfg.matchMoments(context.Background(), collection, "AbCdEfGhIj0", model.SearchState{Query: test.query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(moments, test.want) {
+			t.Errorf("matchMoments(%q) = %v, want %v", test.query, moments, test.want)
+		}
+	}
+}
+
+func TestHandlerPlayer(t *testing.T) {
+	cfg := &Config{
+		searchClient: playerMeilisearch(t, "patiense"),
+		collections:  []model.Collection{{Id: "safina", IndexName: "videos"}},
+	}
+	r := httptest.NewRequest(http.MethodGet, "/player?q=patiense&v=AbCdEfGhIj0&t=120", nil)
+	w := httptest.NewRecorder()
+	cfg.handlerPlayer(w, r)
+
+	body := w.Body.String()
+	if !strings.Contains(body, "3 of 3") {
+		t.Errorf("the moment played is not the last of 3:\n%s", body)
+	}
+	// the controls are buttons so they can be used from the keyboard, the
+	// next match is disabled after the last one
+	if strings.Contains(body, "<a hx-get") || !strings.Contains(body, "disabled") {
+		t.Errorf("the controls are not buttons:\n%s", body)
+	}
+	if !strings.Contains(body, `hx-get="/player?`) || !strings.Contains(body, "t=65") {
+		t.Errorf("the previous match does not load the moment at 65s:\n%s", body)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// cookie set to "1" when the user prefers links to the privacy enhanced
+// youtube player
+const privacyCookie = "privacy"
+
+// cookie set to "1" when the user prefers to play videos in the player on
+// the search page
+const inlinePlayerCookie = "player"
+
+// cookie set to "1" when the user prefers to load more results by
+// scrolling instead of going through pages
+const infiniteScrollCookie = "scroll"
+
+// handlerPreferences saves the preferences submitted from the footer in
+// cookies and sends the user back to the page they were on
+func handlerPreferences(w http.ResponseWriter, r *http.Request) {
+	setPreference(w, privacyCookie, r.FormValue("privacy") != "")
+	setPreference(w, inlinePlayerCookie, r.FormValue("player") != "")
+	setPreference(w, infiniteScrollCookie, r.FormValue("scroll") != "")
+	http.Redirect(w, r, backPath(r), http.StatusSeeOther)
+}
+
+func setPreference(w http.ResponseWriter, name string, enabled bool) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    "1",
+		Path:     "/",
+		MaxAge:   int((365 * 24 * time.Hour).Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if !enabled {
+		cookie.Value = ""
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+}
+
+func cookieSet(r *http.Request, name string) bool {
+	cookie, err := r.Cookie(name)
+	return err == nil && cookie.Value == "1"
+}
+
+// backPath returns the path of the page the request came from. Only a
+// referer on the host of the request is used and only its path and query
+// are kept, a path starting with // or /\ is rejected as browsers follow
+// it to another site
+func backPath(r *http.Request) string {
+	referer, err := url.Parse(r.Referer())
+	if err != nil || (referer.Host != "" && referer.Host != r.Host) || !strings.HasPrefix(referer.Path, "/") ||
+		strings.HasPrefix(referer.Path, "//") || strings.HasPrefix(referer.Path, "/\\") {
+		return "/"
+	}
+	back := url.URL{Path: referer.Path, RawQuery: referer.RawQuery}
+	return back.String()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBackPath(t *testing.T) {
+	tests := []struct {
+		referer string
+		want    string
+	}{
+		{"http://example.com/search?q=patience&page=1", "/search?q=patience&page=1"},
+		{"http://example.com/c/safina/", "/c/safina/"},
+		{"/search?q=patience", "/search?q=patience"},
+		{"", "/"},
+		// another site
+		{"https://evil.com/search?q=patience", "/"},
+		// paths that browsers follow to another site
+		{"https://evil.com//evil.com/x?a=1", "/"},
+		{"http://example.com//evil.com/x?a=1", "/"},
+		{`http://example.com/\evil.com/x`, "/"},
+		{"http://example.com/%5Cevil.com/x", "/"},
+		{"//evil.com/x", "/"},
+		{"javascript:alert(1)", "/"},
+	}
+	for _, test := range tests {
+		t.Run(test.referer, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "http://example.com/preferences", nil)
+			r.Header.Set("Referer", test.referer)
+			w := httptest.NewRecorder()
+			handlerPreferences(w, r)
+			if got := w.Header().Get("Location"); got != test.want {
+				t.Errorf("redirected to %q, want %q", got, test.want)
+			}
+		})
+	}
+}
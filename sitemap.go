@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/link"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/sitemap"
+	"github.com/bevane/safina-society-search/internal/views"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// documents are fetched in smaller pages than when ingesting as the
+// transcripts are fetched to describe each video
+const sitemapPageSize = 100
+
+// sitemapCache keeps the urls of the sitemap until one of the indexes
+// changes
+type sitemapCache struct {
+	mu sync.Mutex
+	// time each index was last updated at when the urls were listed
+	indexesUpdatedAt map[string]time.Time
+	urls             []sitemap.Url
+}
+
+// handlerSitemapIndex lists the chunks of the sitemap
+func (cfg *Config) handlerSitemapIndex(w http.ResponseWriter, r *http.Request) {
+	urls, err := cfg.sitemapUrls(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to list sitemap urls", slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var locs []string
+	for i := range sitemap.Chunks(urls, sitemap.MaxUrls) {
+		locs = append(locs, fmt.Sprintf("%s/sitemaps/%d.xml", cfg.siteUrl, i+1))
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	err = sitemap.WriteIndex(w, locs)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write sitemap index", slog.Any("error", err))
+	}
+}
+
+// handlerSitemap writes a chunk of the sitemap, chunks are numbered from 1
+func (cfg *Config) handlerSitemap(w http.ResponseWriter, r *http.Request) {
+	number, err := strconv.Atoi(strings.TrimSuffix(r.PathValue("chunk"), ".xml"))
+	if err != nil || number < 1 {
+		http.NotFound(w, r)
+		return
+	}
+	urls, err := cfg.sitemapUrls(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to list sitemap urls", slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	chunks := sitemap.Chunks(urls, sitemap.MaxUrls)
+	if number > len(chunks) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	err = sitemap.WriteUrlSet(w, chunks[number-1])
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write sitemap", slog.Any("error", err))
+	}
+}
+
+// handlerRobots keeps crawlers out of search results and other pages that
+// are endless or only used by scripts, the videos are listed in the sitemap
+func (cfg *Config) handlerRobots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, err := fmt.Fprintf(w, `User-agent: *
+Disallow: /search
+Disallow: /c/*/search
+Disallow: /suggest
+Disallow: /c/*/suggest
+Disallow: /click
+Disallow: /player
+Disallow: /video/*/related
+Disallow: /admin
+Disallow: /alerts
+Disallow: /collections
+
+Sitemap: %s/sitemap.xml
+`, cfg.siteUrl)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write robots.txt", slog.Any("error", err))
+	}
+}
+
+// sitemapUrls returns the home and topics pages of the collections and the
+// page of every video. The urls are listed again only when an index has
+// changed since they were last listed, e.g. after a sync
+func (cfg *Config) sitemapUrls(ctx context.Context) ([]sitemap.Url, error) {
+	cfg.sitemap.mu.Lock()
+	defer cfg.sitemap.mu.Unlock()
+
+	indexesUpdatedAt := map[string]time.Time{}
+	for _, collection := range cfg.indexedCollections() {
+		info, err := cfg.searchClient.Index(collection.IndexName).FetchInfoWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		indexesUpdatedAt[collection.IndexName] = info.UpdatedAt
+	}
+	if cfg.sitemap.urls != nil && maps.Equal(indexesUpdatedAt, cfg.sitemap.indexesUpdatedAt) {
+		return cfg.sitemap.urls, nil
+	}
+
+	var urls []sitemap.Url
+	for _, collection := range cfg.collections {
+		urls = append(urls,
+			sitemap.Url{Loc: cfg.siteUrl + collection.HomePath()},
+			sitemap.Url{Loc: cfg.siteUrl + collection.TopicsPath()},
+		)
+	}
+	for _, collection := range cfg.indexedCollections() {
+		videoUrls, err := cfg.videoSitemapUrls(ctx, collection)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, videoUrls...)
+	}
+	slog.InfoContext(ctx, "listed sitemap urls", slog.Int("urls", len(urls)))
+	cfg.sitemap.urls = urls
+	cfg.sitemap.indexesUpdatedAt = indexesUpdatedAt
+	return urls, nil
+}
+
+// videoSitemapUrls returns the urls of the pages of the videos in the index
+// of the collection
+func (cfg *Config) videoSitemapUrls(ctx context.Context, collection model.Collection) ([]sitemap.Url, error) {
+	var urls []sitemap.Url
+	for offset := int64(0); ; offset += sitemapPageSize {
+		result := meilisearch.DocumentsResult{}
+		err := cfg.searchClient.Index(collection.IndexName).GetDocumentsWithContext(ctx, &meilisearch.DocumentsQuery{
+			Offset: offset,
+			Limit:  sitemapPageSize,
+			Fields: []string{"id", "title", "transcript", "uploadDate", "updatedAt"},
+		}, &result)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := json.Marshal(result.Results)
+		if err != nil {
+			return nil, err
+		}
+		var page []model.VideoDocument
+		err = json.Unmarshal(raw, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, document := range page {
+			urls = append(urls, cfg.videoSitemapUrl(collection, document))
+		}
+		if len(page) < sitemapPageSize {
+			return urls, nil
+		}
+	}
+}
+
+func (cfg *Config) videoSitemapUrl(collection model.Collection, document model.VideoDocument) sitemap.Url {
+	video := &sitemap.Video{
+		ThumbnailLoc: link.Thumbnail(document.Id),
+		Title:        document.Title,
+		Description:  videoDescription(collection, document.Title, document.Transcript),
+		PlayerLoc:    link.Video{Id: document.Id}.Embed(false),
+	}
+	url := sitemap.Url{
+		Loc:   cfg.siteUrl + views.VideoPath(document.Id, ""),
+		Video: video,
+	}
+	if published, ok := uploadDate(document); ok {
+		video.PublicationDate = published.Format(time.DateOnly)
+		url.LastMod = video.PublicationDate
+	}
+	if document.UpdatedAt != 0 {
+		url.LastMod = time.Unix(document.UpdatedAt, 0).UTC().Format(time.DateOnly)
+	}
+	return url
+}
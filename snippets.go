@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/transcript"
+)
+
+// number of passages shown for each result
+const passagesPerResult = 3
+
+// number of words of a passage, passages are made of whole lines of the
+// transcript so they can be a little longer
+const passageWords = 30
+
+// srt timing line "00:20:30,500 --> 00:20:33,000", captures the start and
+// the end
+var timingLineRegex = regexp.MustCompile(`^(\d{2}):(\d{2}):(\d{2}),\d{3} -->(?: (\d{2}):(\d{2}):(\d{2}),\d{3})?`)
+
+// transcriptLine is a line of text of a cue in a transcript stored in the
+// index
+type transcriptLine struct {
+	cueStart time.Duration
+	// the same as cueStart if the timing line has no end
+	cueEnd time.Duration
+	// byte range of the text in the transcript, matches positions are byte
+	// offsets
+	start int
+	end   int
+	words int
+}
+
+// transcriptLines returns the text lines of the cues in a transcript in the
+// srt format
+func transcriptLines(srt string) []transcriptLine {
+	var lines []transcriptLine
+	var cueStart, cueEnd time.Duration
+	inCue := false
+	offset := 0
+	for _, line := range strings.SplitAfter(srt, "\n") {
+		start := offset
+		offset += len(line)
+		text := strings.TrimRight(line, " \t\r\n")
+		// the range is of the text without the spaces around it
+		trimmed := strings.TrimLeft(text, " \t")
+		start += len(text) - len(trimmed)
+		text = trimmed
+		if text == "" {
+			inCue = false
+			continue
+		}
+		if match := timingLineRegex.FindStringSubmatch(text); match != nil {
+			cueStart = timingOffset(match[1:4])
+			cueEnd = cueStart
+			if match[4] != "" {
+				cueEnd = timingOffset(match[4:7])
+			}
+			inCue = true
+			continue
+		}
+		if !inCue {
+			continue
+		}
+		lines = append(lines, transcriptLine{
+			cueStart: cueStart,
+			cueEnd:   cueEnd,
+			start:    start,
+			end:      start + len(text),
+			words:    len(strings.Fields(text)),
+		})
+	}
+	return lines
+}
+
+// timingOffset returns the offset of the hours, minutes and seconds of an
+// srt timing
+func timingOffset(parts []string) time.Duration {
+	hours, _ := strconv.Atoi(parts[0])
+	minutes, _ := strconv.Atoi(parts[1])
+	seconds, _ := strconv.Atoi(parts[2])
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+}
+
+type passage struct {
+	first int
+	last  int
+	score float64
+}
+
+// rankedPassages returns up to passagesPerResult passages of the transcript
+// around the matches, best first. Passages score higher the more distinct
+// terms of the query and the more matches they contain relative to their
+// length, so passages where the terms are close together are preferred
+func rankedPassages(ctx context.Context, srt string, matches []model.Position, lang string, videoId string) []model.Snippet {
+	if len(matches) == 0 {
+		return nil
+	}
+	lines := transcriptLines(srt)
+	matches = append([]model.Position(nil), matches...)
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+
+	var candidates []passage
+	for i, line := range lines {
+		if countMatches(matches, line.start, line.end) == 0 {
+			continue
+		}
+		// a passage starts at a line with a match and ends when it is
+		// long enough
+		last := i
+		words := line.words
+		for last+1 < len(lines) && words < passageWords {
+			last++
+			words += lines[last].words
+		}
+		start, end := lines[i].start, lines[last].end
+		terms := map[string]bool{}
+		count := 0
+		for _, match := range matches {
+			if match.Start >= start && match.Start+match.Length <= end {
+				terms[strings.ToLower(srt[match.Start:match.Start+match.Length])] = true
+				count++
+			}
+		}
+		density := float64(count) / float64(max(words, 1))
+		candidates = append(candidates, passage{
+			first: i,
+			last:  last,
+			score: 2*float64(len(terms)) + float64(count) + density,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	var snippets []model.Snippet
+	var chosen []passage
+	for _, candidate := range candidates {
+		if len(chosen) == passagesPerResult {
+			break
+		}
+		overlaps := false
+		for _, c := range chosen {
+			if candidate.first <= c.last && c.first <= candidate.last {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+		chosen = append(chosen, candidate)
+		timestampSeconds := strconv.Itoa(int(lines[candidate.first].cueStart.Seconds()))
+		snippets = append(snippets, model.Snippet{
+			Text:             highlightPassage(srt, lines[candidate.first:candidate.last+1], matches),
+			Timestamp:        transcript.FormatTimestamp(lines[candidate.first].cueStart),
+			TimestampSeconds: timestampSeconds,
+			Url:              videoUrl(ctx, videoId, timestampSeconds, lang),
+		})
+	}
+	return snippets
+}
+
+func countMatches(matches []model.Position, start int, end int) int {
+	count := 0
+	for _, match := range matches {
+		if match.Start >= start && match.Start+match.Length <= end {
+			count++
+		}
+	}
+	return count
+}
+
+// highlightPassage joins the text of the lines, marking the matches in it
+func highlightPassage(srt string, lines []transcriptLine, matches []model.Position) model.HighlightedText {
+	var text model.HighlightedText
+	for i, line := range lines {
+		if i > 0 {
+			text.Append(" ", false)
+		}
+		var lineMatches []model.Position
+		for _, match := range matches {
+			if match.Start >= line.start && match.Start+match.Length <= line.end {
+				lineMatches = append(lineMatches, model.Position{Start: match.Start - line.start, Length: match.Length})
+			}
+		}
+		text.AppendText(model.Highlight(srt[line.start:line.end], lineMatches))
+	}
+	return text
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/model"
+)
+
+// bracketed returns the text with the marked parts in brackets
+func bracketed(h model.HighlightedText) string {
+	var sb strings.Builder
+	for _, part := range h {
+		if part.Marked {
+			sb.WriteString("[" + part.Text + "]")
+		} else {
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+func TestTranscriptLines(t *testing.T) {
+	srt := "1\n00:00:05,000 --> 00:01:05,500\n  first line \nsecond line\n\n" +
+		"2\n01:02:03,000 -->\nno end\n\nstray text\n"
+	lines := transcriptLines(srt)
+	want := []struct {
+		text     string
+		cueStart time.Duration
+		cueEnd   time.Duration
+	}{
+		{"first line", 5 * time.Second, 65 * time.Second},
+		{"second line", 5 * time.Second, 65 * time.Second},
+		{"no end", time.Hour + 2*time.Minute + 3*time.Second, time.Hour + 2*time.Minute + 3*time.Second},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(want))
+	}
+	for i, line := range lines {
+		if text := srt[line.start:line.end]; text != want[i].text || line.cueStart != want[i].cueStart || line.cueEnd != want[i].cueEnd {
+			t.Errorf("line %d = %q %s-%s, want %q %s-%s", i, text, line.cueStart, line.cueEnd, want[i].text, want[i].cueStart, want[i].cueEnd)
+		}
+	}
+}
+
+func TestHighlightPassage(t *testing.T) {
+	srt := "1\n00:00:00,000 --> 00:00:05,000\n<script>alert(1)</script> patience\n\n" +
+		"2\n00:00:05,000 --> 00:00:10,000\nبسم الله &amp; patience\n"
+	at := func(match string) model.Position {
+		return model.Position{Start: strings.Index(srt, match), Length: len(match)}
+	}
+	arabic := at("الله")
+	tests := []struct {
+		name    string
+		matches []model.Position
+		want    string
+	}{
+		{"script", []model.Position{at("<script>"), at("patience")}, "[<script>]alert(1)</script> [patience] بسم الله &amp; patience"},
+		{"multi-byte runes and entities", []model.Position{arabic, at("&amp;")}, "<script>alert(1)</script> patience بسم [الله] [&amp;] patience"},
+		{"inside a rune", []model.Position{{Start: arabic.Start + 1, Length: 3}}, "<script>alert(1)</script> patience بسم الله &amp; patience"},
+		{"overlapping", []model.Position{at("patience"), {Start: at("patience").Start + 2, Length: 4}}, "<script>alert(1)</script> [patience] بسم الله &amp; patience"},
+		{"across lines", []model.Position{{Start: at("patience").Start, Length: 20}}, "<script>alert(1)</script> patience بسم الله &amp; patience"},
+		{"timing line", []model.Position{{Start: 2, Length: 2}}, "<script>alert(1)</script> patience بسم الله &amp; patience"},
+		{"out of range", []model.Position{{Start: len(srt), Length: 8}, {Start: -1, Length: 3}}, "<script>alert(1)</script> patience بسم الله &amp; patience"},
+	}
+	lines := transcriptLines(srt)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := bracketed(highlightPassage(srt, lines, test.matches)); got != test.want {
+				t.Errorf("highlightPassage = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/embedding"
+	"github.com/bevane/safina-society-search/internal/link"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/transcript"
+)
+
+// longest excerpt of a time range shown as the snippet, the transcript
+// page has the full transcript
+const maxExcerptWords = 300
+
+// a time range such as 40m-50m, 1h-1h15m, 45:00-50:00 or 40m-
+var timeRangeRegex = regexp.MustCompile(`^([0-9hms:]+)-([0-9hms:]*)$`)
+
+// an offset with units such as 1h5m or 90s
+var unitOffsetRegex = regexp.MustCompile(`^(?:([0-9]+)h)?(?:([0-9]+)m)?(?:([0-9]+)s)?$`)
+
+// offsets past it are rejected, no video is this long
+const maxOffset = 24 * time.Hour
+
+// videoQuery searches within a single video, optionally within a time
+// range of it, e.g. "id:KwRUYjugvpk 40m-50m arafah"
+type videoQuery struct {
+	VideoId string
+	Start   time.Duration
+	// 0 if the range is open ended
+	End   time.Duration
+	Terms []string
+}
+
+// parseVideoQuery returns the video query if the query starts with
+// id:<video id>
+func parseVideoQuery(query string) (videoQuery, bool) {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return videoQuery{}, false
+	}
+	videoId, ok := strings.CutPrefix(fields[0], "id:")
+	if !ok || !link.ValidVideoId(videoId) {
+		return videoQuery{}, false
+	}
+	vq := videoQuery{VideoId: videoId}
+	rest := fields[1:]
+	if len(rest) > 0 {
+		if start, end, ok := parseTimeRange(rest[0]); ok {
+			vq.Start = start
+			vq.End = end
+			rest = rest[1:]
+		}
+	}
+	vq.Terms = embedding.Tokenize(strings.Join(rest, " "))
+	return vq, true
+}
+
+// parseTimeRange parses start-end where each bound is a duration with
+// units (40m, 1h5m, 90s) or a clock time (45:00, 1:05:00). The end can be
+// left out (40m-) to search until the end of the video
+func parseTimeRange(text string) (time.Duration, time.Duration, bool) {
+	match := timeRangeRegex.FindStringSubmatch(text)
+	if match == nil {
+		return 0, 0, false
+	}
+	start, err := parseOffset(match[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	if match[2] == "" {
+		return start, 0, true
+	}
+	end, err := parseOffset(match[2])
+	if err != nil || end <= start {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// parseOffset parses an offset in a video with units (1h5m) or as a clock
+// time (1:05:00) of at most maxOffset
+func parseOffset(text string) (time.Duration, error) {
+	var seconds int
+	if !strings.Contains(text, ":") {
+		match := unitOffsetRegex.FindStringSubmatch(text)
+		if text == "" || match == nil {
+			return 0, fmt.Errorf("invalid time %q", text)
+		}
+		units := []int{3600, 60, 1}
+		for i, part := range match[1:] {
+			if part == "" {
+				continue
+			}
+			value, err := strconv.Atoi(part)
+			if err != nil || value > int(maxOffset.Seconds()) {
+				return 0, fmt.Errorf("invalid time %q", text)
+			}
+			seconds += value * units[i]
+		}
+	} else {
+		parts := strings.Split(text, ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("invalid time %q", text)
+		}
+		for i, part := range parts {
+			value, err := strconv.Atoi(part)
+			// minutes and seconds after the first part are below 60
+			if err != nil || value < 0 || (i > 0 && value >= 60) || value > int(maxOffset.Seconds()) {
+				return 0, fmt.Errorf("invalid time %q", text)
+			}
+			seconds = seconds*60 + value
+		}
+	}
+	offset := time.Duration(seconds) * time.Second
+	if offset > maxOffset {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	return offset, nil
+}
+
+// getVideoQueryResults searches the cues of the video's transcript within
+// the time range, the video is only looked up in the indexes of the
+// collections searched. Without search terms the excerpt of the whole range
+// is returned, otherwise the cues that contain one of the terms
+func (cfg *Config) getVideoQueryResults(ctx context.Context, collections []model.Collection, vq videoQuery, lang string) (model.Results, error) {
+	document, _, err := cfg.findVideoIn(ctx, collections, vq.VideoId, []string{"id", "title", "transcript", "transcripts"})
+	if errors.Is(err, errVideoNotFound) {
+		return model.Results{}, nil
+	}
+	if err != nil {
+		return model.Results{}, err
+	}
+	hit := model.VideoHit{Transcript: document.Transcript, Transcripts: document.Transcripts}
+	cues, err := transcript.ParseSRT(strings.NewReader(hit.TranscriptIn(lang)))
+	if err != nil {
+		return model.Results{}, err
+	}
+
+	// cues with matches are separated by an ellipsis as they are not
+	// next to each other, the cues of a range are
+	separator := " … "
+	if len(vq.Terms) == 0 {
+		separator = " "
+	}
+	var snippet model.HighlightedText
+	words := 0
+	matches := 0
+	var timestamp time.Duration = -1
+	for _, cue := range cues {
+		if cue.End <= vq.Start || (vq.End != 0 && cue.Start >= vq.End) {
+			continue
+		}
+		text, cueMatches := highlightTerms(cue.Text, vq.Terms)
+		if len(vq.Terms) > 0 && cueMatches == 0 {
+			continue
+		}
+		matches += cueMatches
+		if timestamp < 0 {
+			timestamp = max(cue.Start, vq.Start)
+		}
+		if words < maxExcerptWords {
+			if len(snippet) > 0 {
+				snippet.Append(separator, false)
+			}
+			snippet.AppendText(text)
+			words += len(strings.Fields(cue.Text))
+		}
+	}
+	// nothing was said in the range or none of the terms were
+	if timestamp < 0 {
+		return model.Results{}, nil
+	}
+	if words >= maxExcerptWords {
+		snippet.Append(" …", false)
+	}
+	timestampSeconds := strconv.Itoa(int(timestamp.Seconds()))
+	span := ""
+	if len(vq.Terms) == 0 {
+		span = transcript.FormatTimestamp(vq.Start) + "-"
+		if vq.End != 0 {
+			span += transcript.FormatTimestamp(vq.End)
+		}
+	}
+	return model.Results{
+		Items: []model.Result{{
+			VideoId:          document.Id,
+			Title:            model.PlainText(document.Title),
+			Url:              videoUrl(ctx, document.Id, timestampSeconds, lang),
+			TimestampSeconds: timestampSeconds,
+			ThumbnailUrl:     link.Thumbnail(document.Id),
+			Snippet:          snippet,
+			MatchesCount:     matches,
+			Span:             span,
+			Language:         lang,
+		}},
+		TotalHits: 1,
+	}, nil
+}
+
+// highlightTerms marks the words of the text that are one of the terms, it
+// returns the number of words marked
+func highlightTerms(text string, terms []string) (model.HighlightedText, int) {
+	var highlighted model.HighlightedText
+	matches := 0
+	for i, word := range strings.Fields(text) {
+		if i > 0 {
+			highlighted.Append(" ", false)
+		}
+		marked := slices.ContainsFunc(embedding.Tokenize(word), func(token string) bool {
+			return slices.Contains(terms, token)
+		})
+		if marked {
+			matches++
+		}
+		highlighted.Append(word, marked)
+	}
+	return highlighted, matches
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/model"
+)
+
+func TestParseTimeRange(t *testing.T) {
+	tests := []struct {
+		text       string
+		start, end time.Duration
+		ok         bool
+	}{
+		{"40m-50m", 40 * time.Minute, 50 * time.Minute, true},
+		{"1h-1h15m", time.Hour, 75 * time.Minute, true},
+		{"1h5m30s-2h", time.Hour + 5*time.Minute + 30*time.Second, 2 * time.Hour, true},
+		{"90s-3m", 90 * time.Second, 3 * time.Minute, true},
+		{"45:00-50:00", 45 * time.Minute, 50 * time.Minute, true},
+		{"1:05:00-1:10:30", 65 * time.Minute, 70*time.Minute + 30*time.Second, true},
+		{"45:00-50m", 45 * time.Minute, 50 * time.Minute, true},
+		// open ended
+		{"40m-", 40 * time.Minute, 0, true},
+		{"0:30-", 30 * time.Second, 0, true},
+		// the end is not after the start
+		{"50m-40m", 0, 0, false},
+		{"40m-40m", 0, 0, false},
+		// out of range
+		{"45:60-50:00", 0, 0, false},
+		{"1:60:00-2:00:00", 0, 0, false},
+		{"25h-", 0, 0, false},
+		{"99999999999999999999h-", 0, 0, false},
+		{"1:2:3:4-", 0, 0, false},
+		// not a range
+		{"40m", 0, 0, false},
+		{"-", 0, 0, false},
+		{"-40m", 0, 0, false},
+		{"1.5h-", 0, 0, false},
+		{"10ms-1s", 0, 0, false},
+		{"5m40-6m", 0, 0, false},
+		{"1h:00-2h", 0, 0, false},
+		{"40m-50m-60m", 0, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			start, end, ok := parseTimeRange(test.text)
+			if start != test.start || end != test.end || ok != test.ok {
+				t.Errorf("parseTimeRange = %s, %s, %t, want %s, %s, %t", start, end, ok, test.start, test.end, test.ok)
+			}
+		})
+	}
+}
+
+func TestParseVideoQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  videoQuery
+		ok    bool
+	}{
+		{"id:KwRUYjugvpk 40m-50m Arafah", videoQuery{VideoId: "KwRUYjugvpk", Start: 40 * time.Minute, End: 50 * time.Minute, Terms: []string{"arafah"}}, true},
+		{"id:KwRUYjugvpk 40m-", videoQuery{VideoId: "KwRUYjugvpk", Start: 40 * time.Minute}, true},
+		{"id:KwRUYjugvpk day of arafah", videoQuery{VideoId: "KwRUYjugvpk", Terms: []string{"day", "of", "arafah"}}, true},
+		// only the first word after the id can be a range
+		{"id:KwRUYjugvpk arafah 40m-50m", videoQuery{VideoId: "KwRUYjugvpk", Terms: []string{"arafah", "40m", "50m"}}, true},
+		// an invalid range is searched for
+		{"id:KwRUYjugvpk 50m-40m", videoQuery{VideoId: "KwRUYjugvpk", Terms: []string{"50m", "40m"}}, true},
+		{"id:invalid 40m-50m", videoQuery{}, false},
+		{"arafah id:KwRUYjugvpk", videoQuery{}, false},
+		{"", videoQuery{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			got, ok := parseVideoQuery(test.query)
+			if ok != test.ok || got.VideoId != test.want.VideoId || got.Start != test.want.Start || got.End != test.want.End || !slices.Equal(got.Terms, test.want.Terms) {
+				t.Errorf("parseVideoQuery = %+v, %t, want %+v, %t", got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func TestGetVideoQueryResults(t *testing.T) {
+	const srt = "1\n00:00:00,000 --> 00:00:10,000\nthe mercy of Allah\n\n" +
+		"2\n00:40:05,000 --> 00:40:10,000\nthe day of Arafah\n\n" +
+		"3\n00:49:55,000 --> 00:50:05,000\nfasting on that day\n\n" +
+		"4\n00:55:00,000 --> 00:55:05,000\nArafah again\n"
+	lookups := map[string]int{}
+	client := fakeMeilisearch(t, func(w http.ResponseWriter, r *http.Request) {
+		lookups[r.URL.Path]++
+		// the video is only in the index of the other collection
+		if r.URL.Path != "/indexes/other/documents/KwRUYjugvpk" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			writeJSON(w, map[string]string{"message": "document not found", "code": "document_not_found", "type": "invalid_request"})
+			return
+		}
+		writeJSON(w, map[string]any{"id": "KwRUYjugvpk", "title": "Arafah", "transcript": srt})
+	})
+	safina := model.Collection{Id: "safina", IndexName: "videos"}
+	other := model.Collection{Id: "other", IndexName: "other"}
+	cfg := &Config{searchClient: client, collections: []model.Collection{safina, other}}
+
+	tests := []struct {
+		name        string
+		collections []model.Collection
+		query       string
+		timestamp   string
+		snippet     string
+		span        string
+	}{
+		{"term in the range", []model.Collection{other}, "id:KwRUYjugvpk 40m-50m arafah", "2405", "the day of Arafah", ""},
+		{"term outside the range", []model.Collection{other}, "id:KwRUYjugvpk 40m-50m mercy", "", "", ""},
+		{"excerpt of the range", []model.Collection{other}, "id:KwRUYjugvpk 40m-50m", "2405", "the day of Arafah fasting on that day", "00:40:00-00:50:00"},
+		{"cue across the start", []model.Collection{other}, "id:KwRUYjugvpk 50m-", "3000", "fasting on that day Arafah again", "00:50:00-"},
+		{"whole video", []model.Collection{other}, "id:KwRUYjugvpk arafah", "2405", "the day of Arafah … Arafah again", ""},
+		{"video of another collection", []model.Collection{safina}, "id:KwRUYjugvpk arafah", "", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vq, ok := parseVideoQuery(test.query)
+			if !ok {
+				t.Fatalf("%q is not a video query", test.query)
+			}
+			results, err := cfg.getVideoQueryResults(context.Background(), test.collections, vq, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.timestamp == "" {
+				if len(results.Items) != 0 {
+					t.Errorf("got %+v, want no results", results.Items)
+				}
+				return
+			}
+			if len(results.Items) != 1 {
+				t.Fatalf("got %d results, want 1", len(results.Items))
+			}
+			item := results.Items[0]
+			if item.TimestampSeconds != test.timestamp || item.Snippet.String() != test.snippet || item.Span != test.span {
+				t.Errorf("got timestamp %s snippet %q span %q, want %s %q %q", item.TimestampSeconds, item.Snippet, item.Span, test.timestamp, test.snippet, test.span)
+			}
+		})
+	}
+	if lookups["/indexes/videos/documents/KwRUYjugvpk"] != 1 {
+		t.Errorf("the index of the searched collection was looked up %d times, want 1", lookups["/indexes/videos/documents/KwRUYjugvpk"])
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/views"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// topics of a single video are not listed, they are not a way to browse
+// the catalog
+const minTopicCount = 2
+
+// handlerTopics lists the topics of the videos in a collection with the
+// number of videos about each, linking to the videos about the topic
+func (cfg *Config) handlerTopics(w http.ResponseWriter, r *http.Request) {
+	collection, ok := cfg.collectionFromRequest(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	meta := cfg.pageMeta(collection, collection.TopicsPath())
+	meta.Title = "Topics - " + meta.Title
+	topics, err := getTopics(r.Context(), cfg.searchedCollections(collection), cfg.searchClient)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to get topics", slog.Any("error", err))
+		err = views.TopicsPage(collection, nil, views.InternalError(requestIDFromContext(r.Context())), meta).Render(r.Context(), w)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "unable to render topics page with error", slog.Any("error", err))
+		}
+		return
+	}
+	err = views.TopicsPage(collection, topics, nil, meta).Render(r.Context(), w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to render topics page", slog.Any("error", err))
+	}
+}
+
+// getTopics returns the topics of the videos in the collections from the
+// facet distribution of the topics attribute, most common first
+func getTopics(ctx context.Context, collections []model.Collection, searchClient meilisearch.ServiceManager) ([]model.TopicCount, error) {
+	counts := make([]map[string]int, len(collections))
+	errs := make([]error, len(collections))
+	var wg sync.WaitGroup
+	for i, collection := range collections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counts[i], errs[i] = topicCounts(ctx, collection.IndexName, searchClient)
+		}()
+	}
+	wg.Wait()
+	err := errors.Join(errs...)
+	if err != nil {
+		return nil, err
+	}
+
+	total := map[string]int{}
+	for _, c := range counts {
+		for topic, count := range c {
+			total[topic] += count
+		}
+	}
+	var topics []model.TopicCount
+	for topic, count := range total {
+		if count >= minTopicCount {
+			topics = append(topics, model.TopicCount{Topic: topic, Count: count})
+		}
+	}
+	sort.Slice(topics, func(i, j int) bool {
+		if topics[i].Count != topics[j].Count {
+			return topics[i].Count > topics[j].Count
+		}
+		return topics[i].Topic < topics[j].Topic
+	})
+	return topics, nil
+}
+
+func topicCounts(ctx context.Context, indexName string, searchClient meilisearch.ServiceManager) (map[string]int, error) {
+	resRaw, err := searchClient.Index(indexName).SearchRawWithContext(ctx, "", &meilisearch.SearchRequest{
+		Facets: []string{"topics"},
+		// only the facet distribution is needed
+		Limit:                1,
+		AttributesToRetrieve: []string{"id"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	searchResponse := struct {
+		FacetDistribution map[string]map[string]int `json:"facetDistribution"`
+	}{}
+	err = json.Unmarshal(*resRaw, &searchResponse)
+	if err != nil {
+		return nil, err
+	}
+	return searchResponse.FacetDistribution["topics"], nil
+}
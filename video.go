@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bevane/safina-society-search/internal/ingest"
+	"github.com/bevane/safina-society-search/internal/link"
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/bevane/safina-society-search/internal/transcript"
+	"github.com/bevane/safina-society-search/internal/views"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+var errVideoNotFound = errors.New("video not found")
+
+// handlerVideo shows the transcript of a video, the transcript in the
+// language given by the lang param is shown if there is one
+func (cfg *Config) handlerVideo(w http.ResponseWriter, r *http.Request) {
+	videoId := r.PathValue("id")
+	if !link.ValidVideoId(videoId) {
+		http.NotFound(w, r)
+		return
+	}
+	document, collection, err := cfg.findVideo(r.Context(), videoId, []string{"id", "title", "transcript", "transcripts", "uploadDate"})
+	if errors.Is(err, errVideoNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to get video", slog.String("videoId", videoId), slog.Any("error", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	lang := searchLanguage(r.URL.Query().Get("lang"))
+	hit := model.VideoHit{Transcript: document.Transcript, Transcripts: document.Transcripts}
+	srt := hit.TranscriptIn(lang)
+	if srt == "" {
+		lang = ""
+		srt = document.Transcript
+	}
+	cues, err := transcript.ParseSRT(strings.NewReader(srt))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to parse transcript", slog.String("videoId", videoId), slog.Any("error", err))
+	}
+	video := videoSummary(r.Context(), document.Id, document.Title)
+	video.Language = lang
+	if published, ok := uploadDate(document); ok {
+		video.PublishedAt = published.Format("2 January 2006")
+	}
+	for _, cue := range cues {
+		video.Transcript = append(video.Transcript, model.TranscriptLine{
+			Timestamp: transcript.FormatTimestamp(cue.Start),
+			Url:       videoUrl(r.Context(), document.Id, strconv.Itoa(int(cue.Start.Seconds())), lang),
+			Text:      cue.Text,
+		})
+	}
+	err = views.Video(collection, video, cfg.videoMeta(collection, video, srt)).Render(r.Context(), w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to render video page", slog.Any("error", err))
+	}
+}
+
+// handlerRelated renders the videos related to a video, it is loaded
+// lazily by the video page and under each search result
+func (cfg *Config) handlerRelated(w http.ResponseWriter, r *http.Request) {
+	videoId := r.PathValue("id")
+	if !link.ValidVideoId(videoId) {
+		http.NotFound(w, r)
+		return
+	}
+	document, collection, err := cfg.findVideo(r.Context(), videoId, []string{"id", "related"})
+	if errors.Is(err, errVideoNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to get video", slog.String("videoId", videoId), slog.Any("error", err))
+		err = views.InternalError(requestIDFromContext(r.Context())).Render(r.Context(), w)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "unable to render error component", slog.Any("error", err))
+		}
+		return
+	}
+
+	videos, err := cfg.relatedVideos(r.Context(), collection, document.Related)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to get related videos", slog.String("videoId", videoId), slog.Any("error", err))
+	}
+	err = views.Related(videos).Render(r.Context(), w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to render related videos", slog.Any("error", err))
+	}
+}
+
+// relatedVideos returns the videos with the given ids in the index of the
+// collection in the order of the ids. Related videos are only updated on
+// sync, so videos removed since are skipped
+func (cfg *Config) relatedVideos(ctx context.Context, collection model.Collection, ids []string) ([]model.Video, error) {
+	filter := idFilter(ids)
+	if filter == "" {
+		return nil, nil
+	}
+	searchRequest := meilisearch.SearchRequest{
+		Filter:               filter,
+		AttributesToRetrieve: []string{"id", "title"},
+		Limit:                int64(len(ids)),
+	}
+	response, err := searchIndex(ctx, collection.IndexName, "", &searchRequest, cfg.searchClient)
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[string]string, len(response.Hits))
+	for _, hit := range response.Hits {
+		titles[hit.Id] = hit.Title
+	}
+	var videos []model.Video
+	for _, id := range ids {
+		title, ok := titles[id]
+		if !ok {
+			continue
+		}
+		videos = append(videos, videoSummary(ctx, id, title))
+	}
+	return videos, nil
+}
+
+// findVideo returns the requested fields of a video from the index of the
+// first collection that has it
+func (cfg *Config) findVideo(ctx context.Context, videoId string, fields []string) (model.VideoDocument, model.Collection, error) {
+	return cfg.findVideoIn(ctx, cfg.indexedCollections(), videoId, fields)
+}
+
+// findVideoIn returns the requested fields of a video from the index of the
+// first of the collections that has it
+func (cfg *Config) findVideoIn(ctx context.Context, collections []model.Collection, videoId string, fields []string) (model.VideoDocument, model.Collection, error) {
+	for _, collection := range collections {
+		document := model.VideoDocument{}
+		err := cfg.searchClient.Index(collection.IndexName).GetDocumentWithContext(ctx, videoId, &meilisearch.DocumentQuery{Fields: fields}, &document)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return model.VideoDocument{}, model.Collection{}, err
+		}
+		return document, collection, nil
+	}
+	return model.VideoDocument{}, model.Collection{}, errVideoNotFound
+}
+
+func isNotFound(err error) bool {
+	var meiliErr *meilisearch.Error
+	return errors.As(err, &meiliErr) && meiliErr.StatusCode == http.StatusNotFound
+}
+
+func videoSummary(ctx context.Context, videoId string, title string) model.Video {
+	return model.Video{
+		Id:           videoId,
+		Title:        title,
+		Url:          videoUrl(ctx, videoId, "0", ""),
+		ThumbnailUrl: link.Thumbnail(videoId),
+	}
+}
+
+// uploadDate returns the day the video was published, false if it is not
+// known
+func uploadDate(document model.VideoDocument) (time.Time, bool) {
+	published, err := time.Parse(ingest.UploadDateLayout, document.UploadDate)
+	return published, err == nil
+}
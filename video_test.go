@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bevane/safina-society-search/internal/model"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+func TestHandlerRelated(t *testing.T) {
+	searches := 0
+	client := fakeMeilisearch(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/indexes/videos/documents/AbCdEfGhIj0":
+			writeJSON(w, map[string]any{"id": "AbCdEfGhIj0", "related": []string{"related0001", "removed0001", "related0002"}})
+		case "/indexes/videos/search":
+			searches++
+			var body meilisearch.SearchRequest
+			decodeBody(t, r, &body)
+			want := `id IN ["related0001", "removed0001", "related0002"]`
+			if body.Filter != want || body.Limit != 3 {
+				t.Errorf("filter %v limit %d, want %s limit 3", body.Filter, body.Limit, want)
+			}
+			// hits are not in the order of the ids
+			writeJSON(w, map[string]any{"hits": []map[string]any{
+				{"id": "related0002", "title": "Second"},
+				{"id": "related0001", "title": "First"},
+			}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+	cfg := &Config{
+		searchClient: client,
+		collections:  []model.Collection{{Id: "safina", IndexName: "videos", ChannelName: "Safina Society"}},
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/related/AbCdEfGhIj0", nil)
+	r.SetPathValue("id", "AbCdEfGhIj0")
+	w := httptest.NewRecorder()
+	cfg.handlerRelated(w, r)
+
+	body := w.Body.String()
+	if searches != 1 {
+		t.Errorf("related videos took %d searches, want 1", searches)
+	}
+	first, second := strings.Index(body, "First"), strings.Index(body, "Second")
+	if first < 0 || second < 0 || first > second {
+		t.Errorf("related videos are not listed most related first:\n%s", body)
+	}
+}